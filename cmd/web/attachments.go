@@ -0,0 +1,138 @@
+package main
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Praveen005/snippetbox/internal/models"
+)
+
+// The most attachments a single snippet can have.
+const maxAttachments = 5
+
+// How long a request which can upload attachments or large content has to
+// send its body and get the response back. At 1Mbit/s, the biggest create
+// form takes about four minutes to upload.
+const uploadTimeout = 5 * time.Minute
+
+// The types of file which can be attached to a snippet. We work out the type
+// of each upload from its content using http.DetectContentType(), and ignore
+// whatever the browser claims it is.
+var attachmentTypes = []string{
+	"application/pdf",
+	"application/x-gzip",
+	"application/zip",
+	"image/gif",
+	"image/jpeg",
+	"image/png",
+	"image/webp",
+	"text/plain; charset=utf-8",
+}
+
+// Images are safe for the browser to display directly. Everything else is
+// always downloaded.
+var inlineAttachmentTypes = []string{
+	"image/gif",
+	"image/jpeg",
+	"image/png",
+	"image/webp",
+}
+
+// sniffContentType works out the type of an uploaded file from the first 512
+// bytes of its content.
+func sniffContentType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// attachmentFilename tidies up the filename the browser sent for an upload.
+// Some browsers send the full path, so we keep only the last part of it.
+func attachmentFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" {
+		return "attachment"
+	}
+
+	for utf8.RuneCountInString(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+
+	return name
+}
+
+// storeAttachments writes uploaded files to the blob store, and returns their
+// details ready to be saved with the snippet. contentTypes holds the type we
+// detected for each upload. If it fails part way through, the attachments
+// which were already stored are returned along with the error, so that the
+// caller can clean them up.
+func (app *application) storeAttachments(uploads []*multipart.FileHeader, contentTypes []string) ([]*models.Attachment, error) {
+	attachments := []*models.Attachment{}
+
+	for i, fh := range uploads {
+		f, err := fh.Open()
+		if err != nil {
+			return attachments, err
+		}
+
+		sum, size, err := app.blobs.Put(f)
+		f.Close()
+		if err != nil {
+			return attachments, err
+		}
+
+		attachments = append(attachments, &models.Attachment{
+			SHA256:      sum,
+			Filename:    attachmentFilename(fh.Filename),
+			ContentType: contentTypes[i],
+			Size:        size,
+		})
+	}
+
+	return attachments, nil
+}
+
+// deleteUnusedAttachments deletes the files with the given hashes from the
+// blob store, unless an attachment still refers to them; the same file can be
+// attached to several snippets. Errors are only logged, since the worst that
+// can happen is that a file is left behind.
+//
+// It holds attachmentsMu for writing, so that it can't delete a file which a
+// request has just stored but not yet saved an attachment for.
+func (app *application) deleteUnusedAttachments(sums []string) {
+	if len(sums) == 0 {
+		return
+	}
+
+	app.attachmentsMu.Lock()
+	defer app.attachmentsMu.Unlock()
+
+	unused, err := app.snippets.UnusedAttachments(sums)
+	if err != nil {
+		app.errorLog.Print(err)
+		return
+	}
+
+	for _, sum := range unused {
+		err := app.blobs.Delete(sum)
+		if err != nil {
+			app.errorLog.Print(err)
+		}
+	}
+}
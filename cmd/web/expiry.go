@@ -0,0 +1,45 @@
+package main
+
+import "time"
+
+// An expiryPreset is one of the ready-made choices on the create form for how
+// long a new snippet should live.
+type expiryPreset struct {
+	Value    string
+	Label    string
+	Duration time.Duration
+}
+
+var expiryPresets = []expiryPreset{
+	{Value: "10m", Label: "Ten minutes", Duration: 10 * time.Minute},
+	{Value: "1h", Label: "One hour", Duration: time.Hour},
+	{Value: "1d", Label: "One day", Duration: 24 * time.Hour},
+	{Value: "7d", Label: "One week", Duration: 7 * 24 * time.Hour},
+	{Value: "30d", Label: "One month", Duration: 30 * 24 * time.Hour},
+	{Value: "365d", Label: "One year", Duration: 365 * 24 * time.Hour},
+}
+
+// Besides the presets, the expires field can ask for a snippet which never
+// expires, or one which expires at the date and time in the expires_at field.
+const (
+	expiresNever  = "never"
+	expiresCustom = "custom"
+)
+
+// The layout used by <input type="datetime-local"> fields. Custom expiry times
+// are always in UTC, like every other time we store.
+const expiresAtLayout = "2006-01-02T15:04"
+
+// The furthest ahead a custom expiry time can be. Anything longer than that
+// should simply never expire.
+const maxExpiry = 10 * 365 * 24 * time.Hour
+
+// lookupExpiryPreset returns the preset with the given value, if there is one.
+func lookupExpiryPreset(value string) (expiryPreset, bool) {
+	for _, preset := range expiryPresets {
+		if preset.Value == value {
+			return preset, true
+		}
+	}
+	return expiryPreset{}, false
+}
@@ -3,9 +3,15 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io/fs"
+	"mime"
+	"mime/multipart"
 	"net/http"
-	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Praveen005/snippetbox/internal/diff"
+	"github.com/Praveen005/snippetbox/internal/highlight"
 	"github.com/Praveen005/snippetbox/internal/models"
 	"github.com/Praveen005/snippetbox/internal/validator"
 
@@ -27,10 +33,110 @@ import (
 type snippetCreateForm struct {
 	Title 					string 	`form:"title"`
 	Content 				string 	`form:"content"`
-	Expires 				int 	`form:"expires"`
+	Expires 				string 	`form:"expires"`
+	ExpiresAt 				string 	`form:"expires_at"`
+	Tags 					string 	`form:"tags"`
+	Language 				string 	`form:"language"`
+	Format 					string 	`form:"format"`
+	Visibility 				string 	`form:"visibility"`
+	Password 				string 	`form:"password"`
+	KeepPassword 			bool 	`form:"keep_password"`
+	BurnAfterReading 		bool 	`form:"burn"`
+	ForkedFrom 				string 	`form:"forked_from"`
+	Filename 				string 	`form:"filename"`
+	Files 					[]snippetFileForm `form:"files"`
+	Encrypted 				bool 	`form:"encrypted"`
+	AllowDuplicate 			bool 	`form:"allow_duplicate"`
 	validator.Validator 			`form:"-"`
 }
 
+// The snippetFileForm holds one of the extra files of a multi-file snippet.
+// They're posted with names like "files[0].filename", which the decoder
+// collects into the Files slice of the snippetCreateForm.
+type snippetFileForm struct {
+	Filename string `form:"filename"`
+	Language string `form:"language"`
+	Content  string `form:"content"`
+}
+
+// The most files, including the main one, a single snippet can have.
+const maxFiles = 10
+
+// The most content a snippet can have, in bytes, counting all of its files
+// together. Big content is compressed when it's stored, but this is the size
+// before compression.
+const maxContentSize = 4 << 20
+
+// Diffing takes time in proportion to the number of lines times the number of
+// changes, and memory in proportion to the square of the changes, and anyone
+// can ask for a diff. So texts with more lines than maxDiffLines, or which
+// differ by more than maxDiffEdits lines, are reported as too large instead.
+const (
+	maxDiffLines = 10000
+	maxDiffEdits = 1000
+)
+
+// The error shown for a filename which doesn't match validator.FilenameRx.
+const filenameError = "Filenames can only contain letters, digits and the characters . _ - and be up to 100 characters long, and cannot start with a dot"
+
+// The snippetUnlockForm holds the password entered to unlock a
+// password-protected snippet.
+type snippetUnlockForm struct {
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+// The most tags a single snippet can have.
+const maxTags = 10
+
+// The commentForm holds a new comment on a snippet.
+type commentForm struct {
+	Content             string `form:"content"`
+	validator.Validator `form:"-"`
+}
+
+// The snippetEditForm holds the fields an owner can change after creating a
+// snippet. The expiry time is fixed at creation, so it isn't included.
+type snippetEditForm struct {
+	Title               string `form:"title"`
+	Content             string `form:"content"`
+	validator.Validator `form:"-"`
+}
+
+// The snippetDiffForm holds the query string of the /snippet/diff page. Either
+// A and B are the short IDs of two snippets to compare, or ID is the short ID
+// of a snippet and From and To name two of its revisions.
+type snippetDiffForm struct {
+	A                   string `form:"a"`
+	B                   string `form:"b"`
+	ID                  string `form:"id"`
+	From                int    `form:"from"`
+	To                  int    `form:"to"`
+	validator.Validator `form:"-"`
+}
+
+// The snippetSearchForm holds the query string of the /snippet/search page.
+type snippetSearchForm struct {
+	Q                   string `form:"q"`
+	validator.Validator `form:"-"`
+}
+
+// The snippetListForm holds the query string of the /snippets page. After and
+// Before are opaque cursors produced by SnippetModel.Page().
+type snippetListForm struct {
+	Sort                string `form:"sort"`
+	Size                int    `form:"size"`
+	After               string `form:"after"`
+	Before              string `form:"before"`
+	validator.Validator `form:"-"`
+}
+
+// The largest page size a request to /snippets can ask for.
+const maxPageSize = 100
+
+// The maximum number of results shown for a search.
+const searchLimit = 50
+
 // Create a new userSignupForm struct.
 type userSignupForm struct{
 	Name 		string		`form:"name"`
@@ -58,7 +164,15 @@ func (app *application)home(w http.ResponseWriter, r* http.Request){
 	// }
 
 
-	snippets, err := app.snippets.Latest()
+	snippets, err := app.snippets.Latest(app.pageSize)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+
+	// Fetch the tags for all of the snippets in one go, so that they can be
+	// shown alongside each title.
+	err = app.snippets.LoadTags(snippets...)
 	if err != nil{
 		app.serverError(w, err)
 		return
@@ -80,143 +194,1277 @@ func (app *application)home(w http.ResponseWriter, r* http.Request){
 func (app *application) snippetView(w http.ResponseWriter, r * http.Request){
 
 	// When httprouter is parsing a request, the values of any named parameters
-	// will be stored in the request context. We'll talk about request context
-	// in detail later in the book, but for now it's enough to know that you can
-	// use the ParamsFromContext() function to retrieve a slice containing these
-	// parameter names and values like so:
-	params := httprouter.ParamsFromContext(r.Context())
+	// will be stored in the request context. The liveSnippet() helper uses
+	// ParamsFromContext() to read the snippet's short ID from the ":id"
+	// parameter, then uses the SnippetModel object's GetByShortID method to
+	// retrieve the data for that record.
+	//
+	// If no matching record is found, or the snippet is private and
+	// belongs to somebody else, it sends a 404 Not Found response. We use the
+	// same response for both so that we don't leak the fact that a private
+	// snippet exists. Old URLs with a numeric id are redirected to the short
+	// ID URL instead.
+	snippet, ok := app.liveSnippet(w, r)
+	if !ok{
+		return
+	}
+
+	// If the snippet is password-protected and hasn't been unlocked in this
+	// session yet, show the unlock form instead. We deliberately leave the
+	// snippet's title and content out of the page data.
+	if !app.isUnlocked(r, snippet) {
+		data := app.newTemplateData(r)
+		data.Snippet = &models.Snippet{ShortID: snippet.ShortID}
+		data.Form = snippetUnlockForm{}
+		app.render(w, http.StatusOK, "unlock.tmpl", data)
+		return
+	}
+
+	// Burn-after-reading snippets are only shown in response to a POST from
+	// the confirmation form on burn.tmpl. Otherwise link previews in chat apps
+	// and the like would fetch the page and use up the one and only view.
+	if snippet.BurnAfterReading {
+		data := app.newTemplateData(r)
+		data.Snippet = &models.Snippet{ShortID: snippet.ShortID, BurnAfterReading: true}
+		app.render(w, http.StatusOK, "burn.tmpl", data)
+		return
+	}
+
+	// Use the PopString() method to retrieve the value for the "flash" key.
+	// PopString() also deletes the key and value from the session data, so it
+	// acts like a one-time fetch. If there is no matching key in the session
+	// data this will return the empty string.
+	// flash := app.sessionManager.PopString(r.Context(), "flash")
+
+
+	data, err := app.snippetViewData(r, snippet)
+	if err != nil{
+		app.serverError(w, err)
+		return
+	}
+	data.Form = commentForm{}
+
+	// Count the view. It's only buffered in memory here, and written to the
+	// database in the background, so the count shown lags behind a little.
+	app.views.Add(snippet.ID, 1)
+
+	// Pass the flash message to the template.
+	// data.Flash = flash
+	// Use the render helper
+	app.render(w, http.StatusOK, "view.tmpl", data)
+
+}
+
+// snippetViewData gathers everything view.tmpl shows alongside a snippet: its
+// tags, files and attachments, the snippet it was forked from and its forks,
+// its comments, and whether the current user has starred it.
+func (app *application) snippetViewData(r *http.Request, snippet *models.Snippet) (*templateData, error) {
+	err := app.snippets.LoadTags(snippet)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.snippets.LoadFiles(snippet)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.snippets.LoadAttachments(snippet)
+	if err != nil {
+		return nil, err
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+
+	// If this snippet is a fork, look up the original so that we can link to
+	// it, as long as it's still around and the current user is allowed to see
+	// it.
+	if snippet.ForkedFrom != 0 {
+		parent, err := app.snippets.Get(snippet.ForkedFrom)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			return nil, err
+		}
+		if err == nil && parent.VisibleTo(app.authenticatedUserID(r)) {
+			data.ForkedFrom = parent
+		}
+	}
+
+	data.Forks, err = app.snippets.Forks(snippet.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	data.Comments, err = app.comments.ForSnippet(snippet.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find out whether the current user has starred the snippet, so the
+	// page can show a Star or Unstar button.
+	if userID := app.authenticatedUserID(r); userID != 0 {
+		data.Starred, err = app.stars.Exists(userID, snippet.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// snippetUnlockPost checks the password for a password-protected snippet. On
+// success we remember the unlock in the session data for this snippet only, and
+// send the user back to the snippet's page.
+func (app *application) snippetUnlockPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.liveSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	viewURL := fmt.Sprintf("/snippet/view/%s", snippet.ShortID)
+	if app.isUnlocked(r, snippet) {
+		http.Redirect(w, r, viewURL, http.StatusSeeOther)
+		return
+	}
+
+	var form snippetUnlockForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = &models.Snippet{ShortID: snippet.ShortID}
+
+	form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank")
+
+	if form.Valid() {
+		// Attempts are counted per client IP address and snippet, so that
+		// nobody can guess a password by brute force. Each attempt is counted
+		// before the password is checked, and only forgotten if it turns out
+		// to be right. Once the limit is reached we refuse to even check the
+		// password until the window passes.
+		key := fmt.Sprintf("%s:%d", clientIP(r), snippet.ID)
+		if !app.unlockLimiter.Acquire(key) {
+			form.Password = ""
+			form.AddNonFieldError("Too many failed attempts. Please try again later.")
+			data.Form = form
+			app.render(w, http.StatusTooManyRequests, "unlock.tmpl", data)
+			return
+		}
+
+		err = app.snippets.CheckPassword(snippet.ID, form.Password)
+		if err == nil {
+			app.unlockLimiter.Reset(key)
+		} else if errors.Is(err, models.ErrInvalidCredentials) {
+			form.AddNonFieldError("Incorrect password")
+		} else {
+			app.serverError(w, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		form.Password = ""
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "unlock.tmpl", data)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), unlockKey(snippet), true)
+
+	http.Redirect(w, r, viewURL, http.StatusSeeOther)
+}
+
+// snippetRevealPost shows a burn-after-reading snippet and deletes it in the
+// same step. If somebody else got there first, it's already gone and we send a
+// 404 Not Found response.
+func (app *application) snippetRevealPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.liveSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	viewURL := fmt.Sprintf("/snippet/view/%s", snippet.ShortID)
+	if !app.isUnlocked(r, snippet) || !snippet.BurnAfterReading {
+		http.Redirect(w, r, viewURL, http.StatusSeeOther)
+		return
+	}
+
+	snippet, err := app.snippets.Burn(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// This is the only copy of the content anyone will ever get, so make sure
+	// that browsers and proxies don't keep it around either.
+	w.Header().Set("Cache-Control", "no-store")
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	app.render(w, http.StatusOK, "view.tmpl", data)
+}
+
+// Add a new snippetCreate handler, which for now returns a placeholder
+// response. We'll update this shortly to show a HTML form.
+func(app *application) snippetCreate(w http.ResponseWriter, r *http.Request){
+	data := app.newTemplateData(r)
+
+
+	// Initialize a new createSnippetForm instance and pass it to the template.
+	// Notice how this is also a great opportunity to set any default or
+	// 'initial' values for the form --- here we set the initial value for the 
+	// snippet expiry to one year.
+	// we do this because, till there is no field error, FieldErrors is a nil map, and hence
+	// {{with .Form.FieldErrors.title}} throws error.
+	// By this workaround, we will initialize te other fields with their zero values.    
+	data.Form = snippetCreateForm{
+		Expires: "365d",
+		Format:  models.FormatText,
+		Visibility: models.VisibilityPublic,
+	}
+
+
+
+	// newTemplateCache function is called in main.go, it parses all the pages, how?
+	// Ever page will have the base same(chassy will remain same, the upper body might change, based on which 
+	// page you are viewing. different handlers renders different pages). Also, base.tmpl invokes nav.tmpl,
+	// so nav will remain same on all the pages. So, all the pages have already been parsed when we start our 
+	// program and stored in 'templateCache' as template state(ts), and these are ready to be executed by
+	// render function. below are rendering "create.tmpl" for this handler function, which displays
+	// HTML form. when render is called, it will search for the template state of this page, "create.tmpl"
+	// in templateCache and then execute that template.
+	app.render(w, http.StatusOK, "create.tmpl", data)
+}
+
+
+
+// snippetFork shows the create form pre-filled with a copy of an existing
+// snippet. Saving it creates a brand new snippet owned by the current user,
+// which remembers where it was forked from; the original isn't touched.
+func (app *application) snippetFork(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// We can't read encrypted snippets, so there's nothing to copy into the form.
+	if snippet.Encrypted {
+		app.notFound(w)
+		return
+	}
+
+	err := app.snippets.LoadTags(snippet)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.snippets.LoadFiles(snippet)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	files := []snippetFileForm{}
+	for _, f := range snippet.Files {
+		files = append(files, snippetFileForm{Filename: f.Filename, Language: f.Language, Content: f.Content})
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = snippetCreateForm{
+		Title:      snippet.Title,
+		Content:    snippet.Content,
+		Expires:    "365d",
+		Tags:       strings.Join(snippet.Tags, ", "),
+		Language:   snippet.Language,
+		Format:     snippet.Format,
+		Visibility: snippet.Visibility,
+		ForkedFrom: snippet.ShortID,
+		Filename:   snippet.Filename,
+		Files:      files,
+	}
+	app.render(w, http.StatusOK, "create.tmpl", data)
+}
+
+// Renamed previous snippetCreate() to snippetCreatePost to write to the database
+func(app * application) snippetCreatePost(w http.ResponseWriter, r* http.Request){
+	
+	// Declare a new empty instance of the snippetCreateForm struct.
+	var form snippetCreateForm
+
+	// The create form is sent as multipart/form-data so that it can include
+	// attachments. The parseBody() middleware has already parsed it, which
+	// fills in r.PostForm for decodePostForm() as well as r.MultipartForm.
+	err := app.decodePostForm(r, &form)
+	if err != nil{
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
 
+	// Because the Validator type is embedded(cheeck struct embedding blog) by the snippetCreateForm struct,
+	// we can call CheckField() directly on it to execute our validation checks.
+	// CheckField() will add the provided key and error message to the
+	// FieldErrors map if the check does not evaluate to true. For example, in
+	// the first line here we "check that the form.Title field is not blank". In
+	// the second, we "check that the form.Title field has a maximum character
+	// length of 100" and so on.
+	// I was confused a bit about validator in validor.xyz() below, this validator is the package name. 
+	// yes the package name, you can see nowhere I defined `validator`. We can use it this way,
+	// beacuse we have embedded Validator struct from validator package, in our snippetCreateForm struct.
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+	form.CheckField(validator.MaxBytes(form.Content, maxContentSize), "content", fmt.Sprintf("This field cannot be more than %s", byteSize(maxContentSize)))
+	form.CheckField(validator.PermittedValue(form.Language, highlight.Names()...), "language", "This language is not supported")
+	form.CheckField(validator.PermittedValue(form.Format, models.Formats...), "format", "This field must equal text or markdown")
+	form.CheckField(validator.PermittedValue(form.Visibility, models.Visibilities...), "visibility", "This field must equal public, unlisted or private")
+
+	// Work out when the snippet expires. The zero time means it never does.
+	var expires time.Time
+	switch form.Expires {
+	case expiresNever:
+	case expiresCustom:
+		t, err := time.Parse(expiresAtLayout, form.ExpiresAt)
+		if err != nil {
+			form.AddFieldError("expires_at", "This field must be a date and time")
+			break
+		}
+		form.CheckField(validator.FutureTime(t), "expires_at", "This field must be in the future")
+		form.CheckField(validator.WithinDuration(t, maxExpiry), "expires_at", "This field cannot be more than 10 years away")
+		expires = t
+	default:
+		preset, ok := lookupExpiryPreset(form.Expires)
+		form.CheckField(ok, "expires", "This field must be one of the options shown")
+		expires = time.Now().Add(preset.Duration)
+	}
+
+	// The password is optional, but if one is given it has to be a
+	// reasonable length.
+	if form.Password != "" {
+		form.CheckField(validator.MinChars(form.Password, 8), "password", "This field must be at least 8 characters long")
+	}
+
+	// The password is never sent back to the browser, so when the form is
+	// shown again it has to be typed again. KeepPassword is ticked on such
+	// forms if a password was given, so that resubmitting one without it
+	// doesn't quietly publish the snippet unprotected.
+	if form.KeepPassword {
+		form.CheckField(validator.NotBlank(form.Password), "password", "Please enter the password again, or untick the box to publish without one")
+	}
+
+	// Tags are optional, but each one has to be a sensible name.
+	tags := parseTags(form.Tags)
+	form.CheckField(validator.MaxItems(tags, maxTags), "tags", fmt.Sprintf("A snippet cannot have more than %d tags", maxTags))
+	form.CheckField(validator.AllMatch(tags, validator.TagRx), "tags", "Tags can only contain lower-case letters, digits and the characters _ . + - and be up to 32 characters long")
+
+	// The main file can optionally be given a name. Extra files are optional
+	// too, but each one needs a unique name and some content. Removing a
+	// file in the browser can leave a gap in the numbering, which the decoder
+	// fills with an empty entry, so we drop those first.
+	form.CheckField(form.Filename == "" || validator.Matches(form.Filename, validator.FilenameRx), "filename", filenameError)
+
+	kept := []snippetFileForm{}
+	for _, f := range form.Files {
+		if f != (snippetFileForm{}) {
+			kept = append(kept, f)
+		}
+	}
+	form.Files = kept
+
+	form.CheckField(validator.MaxItems(form.Files, maxFiles-1), "files", fmt.Sprintf("A snippet cannot have more than %d files", maxFiles))
+	form.CheckField(len(form.Files) == 0 || form.Filename != "", "filename", "Name the first file too when there are several")
+
+	files := []*models.File{}
+	seen := map[string]bool{form.Filename: true}
+	size := len(form.Content)
+	for i, f := range form.Files {
+		key := fmt.Sprintf("files[%d]", i)
+		form.CheckField(validator.Matches(f.Filename, validator.FilenameRx), key, filenameError)
+		form.CheckField(!seen[f.Filename], key, "Each file must have a different name")
+		form.CheckField(validator.PermittedValue(f.Language, highlight.Names()...), key, "This language is not supported")
+		form.CheckField(validator.NotBlank(f.Content), key, "A file cannot be empty")
+		seen[f.Filename] = true
+		size += len(f.Content)
+
+		files = append(files, &models.File{Filename: f.Filename, Language: f.Language, Content: f.Content})
+	}
+
+	form.CheckField(size <= maxContentSize, "files", fmt.Sprintf("All the files together cannot be more than %s", byteSize(maxContentSize)))
+
+	// Attachments are optional. We check the size of each one, and look at its
+	// content to make sure it's a type of file we allow.
+	var uploads []*multipart.FileHeader
+	if r.MultipartForm != nil {
+		uploads = r.MultipartForm.File["attachments"]
+	}
+
+	form.CheckField(validator.MaxItems(uploads, maxAttachments), "attachments", fmt.Sprintf("A snippet cannot have more than %d attachments", maxAttachments))
+	form.CheckField(len(uploads) == 0 || !form.BurnAfterReading, "attachments", "Burn-after-reading snippets cannot have attachments")
+
+	contentTypes := make([]string, len(uploads))
+	for i, fh := range uploads {
+		name := attachmentFilename(fh.Filename)
+		if fh.Size > app.maxAttachmentSize {
+			form.AddFieldError("attachments", fmt.Sprintf("%s is too big: attachments can be at most %s", name, byteSize(app.maxAttachmentSize)))
+			continue
+		}
+
+		contentTypes[i], err = sniffContentType(fh)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		form.CheckField(validator.PermittedValue(contentTypes[i], attachmentTypes...), "attachments", fmt.Sprintf("%s is not a type of file which can be attached", name))
+	}
+
+	// Encrypted snippets arrive already encrypted by main.js, with the key
+	// kept in the browser, so all we can check is that the content looks like
+	// ciphertext. We can't highlight or render content we can't read, and
+	// extra files, filenames and attachments would be stored in the clear, so
+	// those aren't allowed.
+	if form.Encrypted {
+		form.CheckField(validator.Matches(form.Content, validator.CiphertextRx), "content", "This field must be encrypted in your browser, which needs JavaScript")
+		form.CheckField(form.Filename == "", "filename", "Encrypted snippets cannot have a filename")
+		form.CheckField(len(form.Files) == 0, "files", "Encrypted snippets cannot have extra files")
+		form.CheckField(len(uploads) == 0, "attachments", "Encrypted snippets cannot have attachments")
+		form.Format = models.FormatText
+		form.Language = ""
+	}
+
+	// If this is a fork, the original has to still be readable by the
+	// current user, just like when the fork form was shown.
+	var forkedFrom int
+	if form.ForkedFrom != "" {
+		source, err := app.visibleSnippet(r, form.ForkedFrom)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, err)
+			return
+		}
+		if err != nil || !app.isUnlocked(r, source) || source.BurnAfterReading || source.Encrypted {
+			form.AddNonFieldError("The snippet you are forking is no longer available")
+		} else {
+			forkedFrom = source.ID
+		}
+	}
+
+	// Use the Valid() method to see if any of the checks failed. If they did,
+	// then re-render the template passing in the form in the same way as
+	// before.
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "create.tmpl", data)
+		return
+	}
+
+	// Pass the data to the SnippetModel.Insert() method, along with the ID of
+	// the logged-in user as the owner. Insert() fills in the ID and ShortID of
+	// the new record for us.
+	snippet := &models.Snippet{
+		UserID:  app.authenticatedUserID(r),
+		Title:   form.Title,
+		Content: form.Content,
+		Expires: expires,
+		Tags:    tags,
+		Language: form.Language,
+		Format:   form.Format,
+		Visibility: form.Visibility,
+		BurnAfterReading: form.BurnAfterReading,
+		ForkedFrom: forkedFrom,
+		Filename: form.Filename,
+		Files:    files,
+		Encrypted: form.Encrypted,
+	}
+
+	// If the user already has a live snippet with exactly the same content,
+	// offer to take them to it instead of creating another copy. They can
+	// still go ahead, which sends the form again with allow_duplicate set.
+	// Encrypted content never matches, and a burn-after-reading snippet is
+	// never the same thing as a lasting one.
+	if !form.AllowDuplicate && !form.Encrypted && !form.BurnAfterReading {
+		duplicate, err := app.snippets.FindDuplicate(snippet.UserID, models.ContentHash(snippet))
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, err)
+			return
+		}
+		if duplicate != nil {
+			if form.Password != "" {
+				form.AddFieldError("password", "Please enter the password again before publishing")
+			}
+			data := app.newTemplateData(r)
+			data.Form = form
+			data.Duplicate = duplicate
+			app.render(w, http.StatusConflict, "create.tmpl", data)
+			return
+		}
+	}
+
+	// Only now that everything else is valid do we write the attachments to
+	// disk. Insert() then saves their details along with the snippet. Until
+	// it has, nothing refers to the files, so the read lock stops them being
+	// cleaned up in the meantime. If anything fails, we clean them up
+	// ourselves.
+	app.attachmentsMu.RLock()
+	snippet.Attachments, err = app.storeAttachments(uploads, contentTypes)
+	if err == nil {
+		_, err = app.snippets.Insert(snippet, form.Password)
+	}
+	app.attachmentsMu.RUnlock()
+
+	if err != nil{
+		sums := []string{}
+		for _, a := range snippet.Attachments {
+			sums = append(sums, a.SHA256)
+		}
+		app.deleteUnusedAttachments(sums)
 
-	// id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		app.serverError(w, err)
+		return
+	}
+
+	// Use the Put() method to add a string value ("Snippet successfully 
+	// created!") and the corresponding key ("flash") to the session data.
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
+
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+
+}
+
+
+// snippetRaw sends the exact stored content of a live snippet as plain text,
+// which makes it easy to fetch with curl. For encrypted snippets that's the
+// ciphertext.
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(snippet.Content))
+}
+
+// snippetDownload works like snippetRaw, but also sets a Content-Disposition
+// header so that browsers save the content as a file named after the snippet.
+func (app *application) snippetDownload(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// A file full of ciphertext isn't much use; the raw URL still serves it.
+	if snippet.Encrypted {
+		app.notFound(w)
+		return
+	}
+
+	// FormatMediaType() takes care of quoting the filename, and of encoding it
+	// properly if the title contained any non-ASCII characters.
+	disposition := mime.FormatMediaType("attachment", map[string]string{
+		"filename": snippetFilename(snippet),
+	})
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", disposition)
+	w.Write([]byte(snippet.Content))
+}
+
+// snippetRawFile sends one of the extra files of a multi-file snippet as plain
+// text. They're numbered from 1; the main file is served by snippetRaw.
+func (app *application) snippetRawFile(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	position, err := readIntParam(r, "file")
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	err = app.snippets.LoadFiles(snippet)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	file, err := snippet.File(position)
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(file.Content))
+}
+
+// snippetAttachment sends one of a snippet's attachments. The URL names the
+// snippet as well as the attachment's hash, so that it's only available to
+// those who can read the snippet.
+func (app *application) snippetAttachment(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	params := httprouter.ParamsFromContext(r.Context())
+
+	attachment, err := app.snippets.GetAttachment(snippet.ID, params.ByName("sha"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	f, err := app.blobs.Open(attachment.SHA256)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+	defer f.Close()
+
+	// Images can be shown in the browser, but everything else is always
+	// downloaded rather than opened. We also make sure the browser never
+	// guesses at a different type, and that nothing in the file can run
+	// scripts on our origin even if it is displayed.
+	disposition := "attachment"
+	if validator.PermittedValue(attachment.ContentType, inlineAttachmentTypes...) {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{
+		"filename": attachment.Filename,
+	}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+
+	// ServeContent() takes care of range requests and If-Modified-Since.
+	http.ServeContent(w, r, "", attachment.Created, f)
+}
+
+// snippetEdit shows the owner of a snippet a form pre-filled with its current
+// title and content.
+func (app *application) snippetEdit(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Encrypted snippets can't be edited, as the server would see the new content.
+	if snippet.Encrypted {
+		app.notFound(w)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Form = snippetEditForm{
+		Title:   snippet.Title,
+		Content: snippet.Content,
+	}
+
+	app.render(w, http.StatusOK, "edit.tmpl", data)
+}
+
+// snippetEditPost validates the edited title and content and saves them as a
+// new revision of the snippet.
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Just like in snippetEdit(), encrypted snippets are off limits.
+	if snippet.Encrypted {
+		app.notFound(w)
+		return
+	}
+
+	var form snippetEditForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// These are the same checks that snippetCreatePost() makes.
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+	form.CheckField(validator.MaxBytes(form.Content, maxContentSize), "content", fmt.Sprintf("This field cannot be more than %s", byteSize(maxContentSize)))
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "edit.tmpl", data)
+		return
+	}
+
+	err = app.snippets.Update(snippet.ID, form.Title, form.Content)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully updated!")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+}
+
+// snippetHistory lists every saved revision of a live snippet.
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Encrypted snippets only ever have the one revision, which is ciphertext.
+	if snippet.Encrypted {
+		app.notFound(w)
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+
+	app.render(w, http.StatusOK, "history.tmpl", data)
+}
+
+// snippetRevision shows a single past revision of a live snippet.
+func (app *application) snippetRevision(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// Nor can their one revision be viewed on its own; see snippetHistory().
+	if snippet.Encrypted {
+		app.notFound(w)
+		return
+	}
+
+	number, err := readIntParam(r, "rev")
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	revision, err := app.snippets.GetRevision(snippet.ID, number)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revision = revision
+
+	app.render(w, http.StatusOK, "revision.tmpl", data)
+}
+
+// snippetRestorePost lets the owner of a snippet make an old revision the
+// current one again.
+func (app *application) snippetRestorePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// An encrypted snippet has nothing to restore.
+	if snippet.Encrypted {
+		app.notFound(w)
+		return
+	}
+
+	number, err := readIntParam(r, "rev")
+	if err != nil {
+		app.notFound(w)
+		return
+	}
+
+	err = app.snippets.Restore(snippet.ID, number)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Revision %d restored!", number))
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+}
+
+// snippetDiff compares either two snippets or two revisions of one snippet,
+// depending on which query string parameters are given. With none, it just
+// shows the forms for choosing what to compare.
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+	var form snippetDiffForm
+
+	// The parameters come from the URL query string rather than a POST body,
+	// but the same decoder can map them into the form struct.
+	err := app.formDecoder.Decode(&form, r.URL.Query())
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data := app.newTemplateData(r)
+
+	var oldText, newText string
+
+	switch {
+	case form.ID != "" || form.From != 0 || form.To != 0:
+		form.CheckField(validator.NotBlank(form.ID), "id", "This field cannot be blank")
+		form.CheckField(form.From > 0, "from", "This field must be a revision number")
+		form.CheckField(form.To > 0, "to", "This field must be a revision number")
+		if !form.Valid() {
+			break
+		}
+
+		// Revisions are only visible while the snippet itself is live and
+		// visible to the current user.
+		snippet, err := app.visibleSnippet(r, form.ID)
+		if err != nil {
+			if !errors.Is(err, models.ErrNoRecord) {
+				app.serverError(w, err)
+				return
+			}
+			form.AddFieldError("id", "No snippet with this ID")
+			break
+		}
+		if !app.isUnlocked(r, snippet) {
+			form.AddFieldError("id", "This snippet is password-protected; unlock it first")
+			break
+		}
+		if snippet.BurnAfterReading {
+			form.AddFieldError("id", "This snippet can only be read once")
+			break
+		}
+		if snippet.Encrypted {
+			form.AddFieldError("id", "This snippet is encrypted, so it can't be compared")
+			break
+		}
+
+		revisions := make([]*models.Revision, 2)
+		for i, number := range []int{form.From, form.To} {
+			revisions[i], err = app.snippets.GetRevision(snippet.ID, number)
+			if err != nil {
+				if !errors.Is(err, models.ErrNoRecord) {
+					app.serverError(w, err)
+					return
+				}
+				form.AddFieldError([]string{"from", "to"}[i], "No revision with this number")
+			}
+		}
+		if !form.Valid() {
+			break
+		}
+
+		sides := make([]diffSide, 2)
+		for i, rev := range revisions {
+			sides[i] = diffSide{
+				Label: fmt.Sprintf("#%s, revision %d", snippet.ShortID, rev.Number),
+				Title: rev.Title,
+				URL:   fmt.Sprintf("/snippet/view/%s/history/%d", snippet.ShortID, rev.Number),
+			}
+		}
+		oldText, newText = revisions[0].Content, revisions[1].Content
+		data.Diff = &snippetDiff{Old: sides[0], New: sides[1]}
+
+	case form.A != "" || form.B != "":
+		form.CheckField(validator.NotBlank(form.A), "a", "This field cannot be blank")
+		form.CheckField(validator.NotBlank(form.B), "b", "This field cannot be blank")
+		if !form.Valid() {
+			break
+		}
+
+		snippets := make([]*models.Snippet, 2)
+		for i, id := range []string{form.A, form.B} {
+			snippets[i], err = app.visibleSnippet(r, id)
+			if err != nil {
+				if !errors.Is(err, models.ErrNoRecord) {
+					app.serverError(w, err)
+					return
+				}
+				form.AddFieldError([]string{"a", "b"}[i], "No snippet with this ID")
+				continue
+			}
+			if !app.isUnlocked(r, snippets[i]) {
+				form.AddFieldError([]string{"a", "b"}[i], "This snippet is password-protected; unlock it first")
+			} else if snippets[i].BurnAfterReading {
+				form.AddFieldError([]string{"a", "b"}[i], "This snippet can only be read once")
+			} else if snippets[i].Encrypted {
+				form.AddFieldError([]string{"a", "b"}[i], "This snippet is encrypted, so it can't be compared")
+			}
+		}
+		if !form.Valid() {
+			break
+		}
+
+		sides := make([]diffSide, 2)
+		for i, snippet := range snippets {
+			sides[i] = diffSide{
+				Label: fmt.Sprintf("#%s", snippet.ShortID),
+				Title: snippet.Title,
+				URL:   fmt.Sprintf("/snippet/view/%s", snippet.ShortID),
+			}
+		}
+		oldText, newText = snippets[0].Content, snippets[1].Content
+		data.Diff = &snippetDiff{Old: sides[0], New: sides[1]}
+	}
+
+	data.Form = form
+
+	if !form.Valid() {
+		app.render(w, http.StatusUnprocessableEntity, "diff.tmpl", data)
+		return
+	}
+
+	if data.Diff != nil {
+		if strings.Count(oldText, "\n") >= maxDiffLines || strings.Count(newText, "\n") >= maxDiffLines {
+			data.Diff.TooLarge = true
+		} else {
+			data.Diff.Lines, err = diff.Lines(oldText, newText, maxDiffEdits)
+			if errors.Is(err, diff.ErrTooDifferent) {
+				data.Diff.TooLarge = true
+			} else if err != nil {
+				app.serverError(w, err)
+				return
+			}
+			data.Diff.Rows = diff.SideBySide(data.Diff.Lines)
+		}
+	}
+
+	app.render(w, http.StatusOK, "diff.tmpl", data)
+}
+
+// snippetSearch runs a full-text search over the live snippets and shows the
+// best matches with highlighted excerpts.
+func (app *application) snippetSearch(w http.ResponseWriter, r *http.Request) {
+	var form snippetSearchForm
+
+	err := app.formDecoder.Decode(&form, r.URL.Query())
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data := app.newTemplateData(r)
+
+	// With no query there is nothing to search for, so just show the form.
+	if !validator.NotBlank(form.Q) {
+		data.Form = form
+		app.render(w, http.StatusOK, "search.tmpl", data)
+		return
+	}
+
+	form.CheckField(validator.MaxChars(form.Q, 100), "q", "This field cannot be more than 100 characters long")
+
+	if !form.Valid() {
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "search.tmpl", data)
+		return
+	}
+
+	results, err := app.snippets.Search(form.Q, searchLimit)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data.Form = form
+	data.SearchResults = results
+
+	app.render(w, http.StatusOK, "search.tmpl", data)
+}
+
+// snippetList shows one page of live snippets, with links to the newer and
+// older pages either side of it.
+func (app *application) snippetList(w http.ResponseWriter, r *http.Request) {
+	form := snippetListForm{
+		Sort: models.SortNewest,
+		Size: app.pageSize,
+	}
+
+	// Decoding over the defaults means any parameter left out of the query
+	// string keeps its default value.
+	err := app.formDecoder.Decode(&form, r.URL.Query())
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.PermittedValue(form.Sort, models.SortOrders...), "sort", "Unknown sort order")
+	form.CheckField(form.Size >= 1 && form.Size <= maxPageSize, "size", fmt.Sprintf("Page size must be between 1 and %d", maxPageSize))
+	form.CheckField(form.After == "" || form.Before == "", "after", "Only one of after and before may be given")
+
+	if !form.Valid() {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	snippets, pagination, err := app.snippets.Page(models.PageParams{
+		Sort:   form.Sort,
+		Size:   form.Size,
+		After:  form.After,
+		Before: form.Before,
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			app.clientError(w, http.StatusBadRequest)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	err = app.snippets.LoadTags(snippets...)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = form
+	data.Snippets = snippets
+	data.Pagination = pagination
+
+	app.render(w, http.StatusOK, "snippets.tmpl", data)
+}
+
+// tagView lists every live snippet with the tag named in the URL.
+func (app *application) tagView(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	tag := params.ByName("name")
+	if !validator.Matches(tag, validator.TagRx) {
+		app.notFound(w)
+		return
+	}
+
+	snippets, err := app.snippets.ByTag(tag)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.snippets.LoadTags(snippets...)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Tag = tag
+	data.Snippets = snippets
+
+	app.render(w, http.StatusOK, "tag.tmpl", data)
+}
+
+// snippetHash lists the live snippets whose content has the SHA-256 hash in
+// the URL, so that identical snippets can be found without searching.
+func (app *application) snippetHash(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	hash := strings.ToLower(params.ByName("sha"))
+	if !validator.Matches(hash, validator.SHA256Rx) {
+		app.notFound(w)
+		return
+	}
+
+	snippets, err := app.snippets.ByContentHash(hash, app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	err = app.snippets.LoadTags(snippets...)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Hash = hash
+	data.Snippets = snippets
+
+	app.render(w, http.StatusOK, "hash.tmpl", data)
+}
+
+// userSnippets shows the logged-in user a dashboard of all the snippets they
+// own, including the ones which have already expired.
+func (app *application) userSnippets(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.ByUser(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+
+	app.render(w, http.StatusOK, "mysnippets.tmpl", data)
+}
+
+// snippetCommentPost adds a comment by the current user to a snippet. If the
+// comment isn't valid, the snippet's page is shown again with the errors.
+func (app *application) snippetCommentPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
 
-	// We can then use the ByName() method to get the value of the "id" named
-	// parameter from the slice and validate it as normal.
-	id, err := strconv.Atoi(params.ByName("id"))
+	var form commentForm
 
-	if err != nil || id < 1{
-		// http.NotFound(w, r) //sends a 404 response
-		app.notFound(w) //notFound() function from helpers.go
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	// Use the SnippetModel object's Get method to retrieve the data for a
-	// specific record based on its ID. If no matching record is found,
-	// return a 404 Not Found response.
-	snippet, err := app.snippets.Get(id)
-	if err != nil{
-		if errors.Is(err, models.ErrNoRecord){
-			app.notFound(w)
-		}else{
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Content, 2000), "content", "This field cannot be more than 2000 characters long")
+
+	if !form.Valid() {
+		data, err := app.snippetViewData(r, snippet)
+		if err != nil {
 			app.serverError(w, err)
+			return
 		}
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "view.tmpl", data)
 		return
 	}
 
-	// Use the PopString() method to retrieve the value for the "flash" key.
-	// PopString() also deletes the key and value from the session data, so it
-	// acts like a one-time fetch. If there is no matching key in the session
-	// data this will return the empty string.
-	// flash := app.sessionManager.PopString(r.Context(), "flash")
-
-
-	data := app.newTemplateData(r)
-	data.Snippet = snippet
+	_, err = app.comments.Insert(snippet.ID, app.authenticatedUserID(r), form.Content)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
 
-	// Pass the flash message to the template.
-	// data.Flash = flash
-	// Use the render helper
-	app.render(w, http.StatusOK, "view.tmpl", data)
+	app.sessionManager.Put(r.Context(), "flash", "Your comment has been added.")
 
+	http.Redirect(w, r, commentsURL(snippet), http.StatusSeeOther)
 }
 
-// Add a new snippetCreate handler, which for now returns a placeholder
-// response. We'll update this shortly to show a HTML form.
-func(app *application) snippetCreate(w http.ResponseWriter, r *http.Request){
-	data := app.newTemplateData(r)
-
+// snippetCommentDeletePost deletes one of the current user's own comments.
+func (app *application) snippetCommentDeletePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.liveSnippet(w, r)
+	if !ok {
+		return
+	}
 
-	// Initialize a new createSnippetForm instance and pass it to the template.
-	// Notice how this is also a great opportunity to set any default or
-	// 'initial' values for the form --- here we set the initial value for the 
-	// snippet expiry to 365 days.
-	// we do this because, till there is no field error, FieldErrors is a nil map, and hence
-	// {{with .Form.FieldErrors.title}} throws error.
-	// By this workaround, we will initialize te other fields with their zero values.    
-	data.Form = snippetCreateForm{
-		Expires: 365,
+	commentID, err := readIntParam(r, "comment")
+	if err != nil {
+		app.notFound(w)
+		return
 	}
 
+	err = app.comments.Delete(commentID, snippet.ID, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
 
+	app.sessionManager.Put(r.Context(), "flash", "Your comment has been deleted.")
 
-	// newTemplateCache function is called in main.go, it parses all the pages, how?
-	// Ever page will have the base same(chassy will remain same, the upper body might change, based on which 
-	// page you are viewing. different handlers renders different pages). Also, base.tmpl invokes nav.tmpl,
-	// so nav will remain same on all the pages. So, all the pages have already been parsed when we start our 
-	// program and stored in 'templateCache' as template state(ts), and these are ready to be executed by
-	// render function. below are rendering "create.tmpl" for this handler function, which displays
-	// HTML form. when render is called, it will search for the template state of this page, "create.tmpl"
-	// in templateCache and then execute that template.
-	app.render(w, http.StatusOK, "create.tmpl", data)
+	http.Redirect(w, r, commentsURL(snippet), http.StatusSeeOther)
 }
 
-
-
-// Renamed previous snippetCreate() to snippetCreatePost to write to the database
-func(app * application) snippetCreatePost(w http.ResponseWriter, r* http.Request){
-	
-	// Declare a new empty instance of the snippetCreateForm struct.
-	var form snippetCreateForm
-
-
-	err := app.decodePostForm(r, &form)
-	if err != nil{
-		app.clientError(w, http.StatusBadRequest)
+// userStars lists the snippets the current user has starred.
+func (app *application) userStars(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.StarredBy(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
 		return
 	}
 
+	err = app.snippets.LoadTags(snippets...)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
 
-	// Because the Validator type is embedded(cheeck struct embedding blog) by the snippetCreateForm struct,
-	// we can call CheckField() directly on it to execute our validation checks.
-	// CheckField() will add the provided key and error message to the
-	// FieldErrors map if the check does not evaluate to true. For example, in
-	// the first line here we "check that the form.Title field is not blank". In
-	// the second, we "check that the form.Title field has a maximum character
-	// length of 100" and so on.
-	// I was confused a bit about validator in validor.xyz() below, this validator is the package name. 
-	// yes the package name, you can see nowhere I defined `validator`. We can use it this way,
-	// beacuse we have embedded Validator struct from validator package, in our snippetCreateForm struct.
-	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
-	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
-	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
-	form.CheckField(validator.PermittedInt(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
 
+	app.render(w, http.StatusOK, "stars.tmpl", data)
+}
 
-	// Use the Valid() method to see if any of the checks failed. If they did,
-	// then re-render the template passing in the form in the same way as
-	// before.
-	if !form.Valid() {
-		data := app.newTemplateData(r)
-		data.Form = form
-		app.render(w, http.StatusUnprocessableEntity, "create.tmpl", data)
+// snippetStarPost stars a snippet for the current user, and sends them back to
+// the snippet's page.
+func (app *application) snippetStarPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
 		return
 	}
 
-
-	// Pass the data to the SnippetModel.Insert() method, receiving the
-	// ID of the new record back.
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
-	if err != nil{
+	err := app.stars.Star(app.authenticatedUserID(r), snippet.ID)
+	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 
-	// Use the Put() method to add a string value ("Snippet successfully 
-	// created!") and the corresponding key ("flash") to the session data.
-	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+}
 
+// snippetUnstarPost removes the current user's star from a snippet.
+func (app *application) snippetUnstarPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.liveSnippet(w, r)
+	if !ok {
+		return
+	}
 
-	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+	err := app.stars.Unstar(app.authenticatedUserID(r), snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
 
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
 }
 
 
@@ -369,3 +1617,307 @@ func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+
+// The collectionCreateForm holds the name of a new collection.
+type collectionCreateForm struct {
+	Title               string `form:"title"`
+	validator.Validator `form:"-"`
+}
+
+// The collectionAddForm holds the short ID of a snippet to add to a
+// collection.
+type collectionAddForm struct {
+	Snippet             string `form:"snippet"`
+	validator.Validator `form:"-"`
+}
+
+// userCollections lists the current user's collections, along with a form to
+// create a new one.
+func (app *application) userCollections(w http.ResponseWriter, r *http.Request) {
+	collections, err := app.collections.ByUser(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Collections = collections
+	data.Form = collectionCreateForm{}
+
+	app.render(w, http.StatusOK, "collections.tmpl", data)
+}
+
+// userCollectionsPost creates a new, empty collection and takes the user to
+// its page to start adding snippets.
+func (app *application) userCollectionsPost(w http.ResponseWriter, r *http.Request) {
+	var form collectionCreateForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
+
+	userID := app.authenticatedUserID(r)
+
+	if !form.Valid() {
+		collections, err := app.collections.ByUser(userID)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+
+		data := app.newTemplateData(r)
+		data.Collections = collections
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "collections.tmpl", data)
+		return
+	}
+
+	shortID, err := app.collections.Insert(userID, form.Title)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Collection successfully created!")
+
+	http.Redirect(w, r, fmt.Sprintf("/collection/%s", shortID), http.StatusSeeOther)
+}
+
+// collectionViewData gathers everything collection.tmpl needs to show a
+// collection: its snippets, in order, and for its owner the snippets they
+// could add to it.
+func (app *application) collectionViewData(r *http.Request, collection *models.Collection) (*templateData, error) {
+	snippets, err := app.snippets.InCollection(collection.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Anyone with the link can see a collection, but private snippets in it
+	// are left out for everybody except their owner, and password-protected
+	// ones are only linked to until they've been unlocked.
+	userID := app.authenticatedUserID(r)
+
+	data := app.newTemplateData(r)
+	data.Collection = collection
+
+	shown := []*models.Snippet{}
+	for _, s := range snippets {
+		if !s.VisibleTo(userID) {
+			continue
+		}
+
+		entry := &collectionEntry{Snippet: s, Locked: !app.isUnlocked(r, s)}
+		if !entry.Locked {
+			err = app.snippets.LoadFiles(s)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		data.Entries = append(data.Entries, entry)
+		shown = append(shown, s)
+	}
+
+	err = app.snippets.LoadTags(shown...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Offer the owner the rest of their live snippets to add. Burn-after-
+	// reading snippets can't go in a collection, as nobody could read them.
+	if userID != 0 && userID == collection.UserID {
+		owned, err := app.snippets.ByUser(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		in := make(map[int]bool, len(snippets))
+		for _, s := range snippets {
+			in[s.ID] = true
+		}
+
+		for _, s := range owned {
+			if !s.Expired() && !s.BurnAfterReading && !in[s.ID] {
+				data.Snippets = append(data.Snippets, s)
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// collectionView shows a collection, rendering each of its snippets in turn.
+func (app *application) collectionView(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.liveCollection(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := app.collectionViewData(r, collection)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	data.Form = collectionAddForm{}
+
+	app.render(w, http.StatusOK, "collection.tmpl", data)
+}
+
+// collectionAddPost adds one of the user's own snippets to the end of their
+// collection.
+func (app *application) collectionAddPost(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+
+	var form collectionAddForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Look the snippet up and check that it's one the user could have picked
+	// from the list. Snippets belonging to somebody else are treated the same
+	// as missing ones.
+	snippet, err := app.snippets.GetByShortID(form.Snippet)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, err)
+		return
+	}
+
+	form.CheckField(snippet != nil && snippet.UserID == collection.UserID, "snippet", "Choose one of your snippets")
+	if snippet != nil {
+		form.CheckField(!snippet.BurnAfterReading, "snippet", "Burn-after-reading snippets can't be added to a collection")
+	}
+
+	if !form.Valid() {
+		data, err := app.collectionViewData(r, collection)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		data.Form = form
+		app.render(w, http.StatusUnprocessableEntity, "collection.tmpl", data)
+		return
+	}
+
+	err = app.collections.AddSnippet(collection.ID, snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet added to the collection.")
+
+	http.Redirect(w, r, fmt.Sprintf("/collection/%s", collection.ShortID), http.StatusSeeOther)
+}
+
+// collectionSnippet looks up the live snippet whose short ID is in the
+// ":snippet" parameter, for the handlers which change a collection's snippets.
+// It sends a 404 Not Found response and returns false if there isn't one.
+func (app *application) collectionSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	snippet, err := app.snippets.GetByShortID(params.ByName("snippet"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// collectionRemovePost takes a snippet out of a collection. The snippet itself
+// isn't touched.
+func (app *application) collectionRemovePost(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+
+	snippet, ok := app.collectionSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.collections.RemoveSnippet(collection.ID, snippet.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/collection/%s", collection.ShortID), http.StatusSeeOther)
+}
+
+// collectionMovePost moves a snippet one place up or down a collection,
+// depending on the "direction" form field.
+func (app *application) collectionMovePost(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+
+	snippet, ok := app.collectionSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var up bool
+	switch r.PostForm.Get("direction") {
+	case "up":
+		up = true
+	case "down":
+		up = false
+	default:
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	err = app.collections.MoveSnippet(collection.ID, snippet.ID, up)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/collection/%s#snippet-%s", collection.ShortID, snippet.ShortID), http.StatusSeeOther)
+}
+
+// collectionDeletePost deletes a whole collection, leaving its snippets alone.
+func (app *application) collectionDeletePost(w http.ResponseWriter, r *http.Request) {
+	collection, ok := app.ownedCollection(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.collections.Delete(collection.ID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Collection deleted.")
+
+	http.Redirect(w, r, "/user/collections", http.StatusSeeOther)
+}
@@ -4,11 +4,20 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"regexp"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
+
+	"github.com/Praveen005/snippetbox/internal/highlight"
+	"github.com/Praveen005/snippetbox/internal/models"
 
 	"github.com/go-playground/form/v4"
+	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/nosurf"
 )
 
@@ -85,6 +94,9 @@ func(app *application) newTemplateData(r *http.Request) *templateData{
 		// Add the authentication status to the template data.
 		IsAuthenticated: app.isAuthenticated(r),
 		CSRFToken: nosurf.Token(r),  // Add the CSRF token.
+		// And the ID of the logged-in user, so templates can decide who owns
+		// what.
+		AuthenticatedUserID: app.authenticatedUserID(r),
 	}
 }
 
@@ -136,4 +148,278 @@ func(app *application) isAuthenticated(r *http.Request) bool {
 
 	return isAuthenticated
 	// return app.sessionManager.Exists(r.Context(), "authenticatedUserID") // no need to check the session data and make an additional db call.
-}
\ No newline at end of file
+}
+
+// Return the ID of the currently logged-in user from the session, or 0 if
+// nobody is logged in. Like isAuthenticated(), this relies on the authenticate()
+// middleware having checked that the user still exists.
+func (app *application) authenticatedUserID(r *http.Request) int {
+	if !app.isAuthenticated(r) {
+		return 0
+	}
+	return app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+}
+
+// readIntParam reads the named httprouter parameter from the request and
+// converts it to a positive integer. An error is returned if it isn't one, in
+// which case handlers should respond with a 404 Not Found.
+func readIntParam(r *http.Request, name string) (int, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	n, err := strconv.Atoi(params.ByName(name))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s parameter", name)
+	}
+
+	return n, nil
+}
+
+// visibleSnippet fetches a live snippet by its short ID on behalf of the
+// current user. It returns models.ErrNoRecord both when the snippet doesn't
+// exist and when it's private to somebody else, so callers treat the two cases
+// identically.
+func (app *application) visibleSnippet(r *http.Request, shortID string) (*models.Snippet, error) {
+	snippet, err := app.snippets.GetByShortID(shortID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		return nil, models.ErrNoRecord
+	}
+
+	return snippet, nil
+}
+
+// liveSnippet looks up the live snippet whose short ID is in the ":id"
+// parameter. If it doesn't exist, has expired or is private to somebody else,
+// it sends a 404 Not Found response (or a 500 if something went wrong) and
+// returns false, so callers can simply return. Old numeric ids are redirected
+// to the short ID URL, which also returns false.
+func (app *application) liveSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id := params.ByName("id")
+	if models.IsLegacyID(id) {
+		app.redirectLegacySnippet(w, r, id)
+		return nil, false
+	}
+
+	// Private snippets get a 404 rather than a 403, so that we don't even
+	// confirm to other users that they exist.
+	snippet, err := app.visibleSnippet(r, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// redirectLegacySnippet handles URLs which still contain a snippet's old
+// numeric id, by permanently redirecting to the same URL with the snippet's
+// short ID in its place. Only GET requests are redirected.
+func (app *application) redirectLegacySnippet(w http.ResponseWriter, r *http.Request, id string) {
+	n, err := strconv.Atoi(id)
+	if err != nil || n < 1 || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		app.notFound(w)
+		return
+	}
+
+	// Only snippets from before short IDs existed have a numeric URL at all.
+	// Otherwise counting up through ids would still list every public snippet
+	// and show how many there are.
+	snippet, err := app.snippets.GetLegacy(n)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return
+	}
+
+	// Only public snippets (and the owner's own snippets) resolve this way.
+	// Otherwise anybody could still find unlisted snippets by counting up
+	// through the old ids, which is what short IDs are meant to prevent.
+	userID := app.authenticatedUserID(r)
+	if snippet.Visibility != models.VisibilityPublic && (userID == 0 || userID != snippet.UserID) {
+		app.notFound(w)
+		return
+	}
+
+	// The id is always the first numeric path segment, as every route puts it
+	// straight after a fixed prefix like /snippet/view/.
+	u := *r.URL
+	u.Path = strings.Replace(u.Path, "/"+id, "/"+snippet.ShortID, 1)
+	u.RawPath = ""
+
+	http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+}
+
+// unlockKey returns the session key which records that the current session has
+// unlocked a particular password-protected snippet.
+func unlockKey(s *models.Snippet) string {
+	return fmt.Sprintf("unlockedSnippet:%d", s.ID)
+}
+
+// isUnlocked reports whether the current user may read the content of a
+// snippet: either it has no password, they own it, or they've already entered
+// its password during this session.
+func (app *application) isUnlocked(r *http.Request, s *models.Snippet) bool {
+	if !s.HasPassword {
+		return true
+	}
+
+	userID := app.authenticatedUserID(r)
+	if userID != 0 && userID == s.UserID {
+		return true
+	}
+
+	return app.sessionManager.GetBool(r.Context(), unlockKey(s))
+}
+
+// readableSnippet works like liveSnippet, for the pages which show a snippet's
+// content in some other form (raw, as a download, its history and so on). It
+// also requires password-protected snippets to have been unlocked, and
+// burn-after-reading snippets can only ever be read through the reveal form.
+// In either case it redirects to the snippet's page, which shows the unlock or
+// reveal form.
+func (app *application) readableSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, ok := app.liveSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if !app.isUnlocked(r, snippet) || snippet.BurnAfterReading {
+		http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.ShortID), http.StatusSeeOther)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// clientIP returns the IP address of the client which made the request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ownedSnippet works like liveSnippet, but also checks that the snippet belongs
+// to the logged-in user, sending a 403 Forbidden response if it doesn't.
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, ok := app.liveSnippet(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if snippet.UserID == 0 || snippet.UserID != app.authenticatedUserID(r) {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// commentsURL returns where to send the user after they've added or deleted a
+// comment on a snippet: the comments on its page. The key to an encrypted
+// snippet is in the URL fragment, which the browser carries over a redirect
+// only if the new URL doesn't have a fragment of its own, so for those we
+// have to do without the #comments.
+func commentsURL(s *models.Snippet) string {
+	if s.Encrypted {
+		return fmt.Sprintf("/snippet/view/%s", s.ShortID)
+	}
+	return fmt.Sprintf("/snippet/view/%s#comments", s.ShortID)
+}
+
+// liveCollection looks up the collection whose short ID is in the ":id"
+// parameter. If there's no such collection it sends a 404 Not Found response
+// (or a 500 if something went wrong) and returns false.
+func (app *application) liveCollection(w http.ResponseWriter, r *http.Request) (*models.Collection, bool) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	collection, err := app.collections.Get(params.ByName("id"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, err)
+		}
+		return nil, false
+	}
+
+	return collection, true
+}
+
+// ownedCollection works like liveCollection, but also checks that the
+// collection belongs to the logged-in user, sending a 403 Forbidden response
+// if it doesn't.
+func (app *application) ownedCollection(w http.ResponseWriter, r *http.Request) (*models.Collection, bool) {
+	collection, ok := app.liveCollection(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if collection.UserID != app.authenticatedUserID(r) {
+		app.clientError(w, http.StatusForbidden)
+		return nil, false
+	}
+
+	return collection, true
+}
+
+// parseTags splits the tags field of a form into individual tag names. Tags
+// can be separated by commas or spaces, are lower-cased, and duplicates are
+// dropped. Whether the names are valid is left to the validator.
+func parseTags(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, tag := range fields {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// nonSlugRx matches the runs of characters which snippetFilename replaces with
+// a dash.
+var nonSlugRx = regexp.MustCompile(`[^a-z0-9]+`)
+
+// snippetFilename returns a sensible filename for downloading a snippet. That's
+// the name of its main file if it has one, or otherwise a name made from its
+// title and an extension for its language, like "my-query.sql".
+func snippetFilename(s *models.Snippet) string {
+	if s.Filename != "" {
+		return s.Filename
+	}
+
+	slug := strings.Trim(nonSlugRx.ReplaceAllString(strings.ToLower(s.Title), "-"), "-")
+	if len(slug) > 50 {
+		slug = strings.TrimRight(slug[:50], "-")
+	}
+	if slug == "" {
+		slug = "snippet-" + s.ShortID
+	}
+
+	extension := highlight.Lookup(s.Language).Extension
+	if s.IsMarkdown() {
+		extension = "md"
+	}
+
+	return slug + "." + extension
+}
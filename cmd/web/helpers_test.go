@@ -0,0 +1,119 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Praveen005/snippetbox/internal/models"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "Empty",
+			input: "",
+			want:  []string{},
+		},
+		{
+			name:  "Only separators",
+			input: " , ,\t\n",
+			want:  []string{},
+		},
+		{
+			name:  "Commas",
+			input: "go,sql,config",
+			want:  []string{"go", "sql", "config"},
+		},
+		{
+			name:  "Spaces and commas",
+			input: "  go, sql  config,,docker ",
+			want:  []string{"go", "sql", "config", "docker"},
+		},
+		{
+			name:  "Lower-cased",
+			input: "Go SQL",
+			want:  []string{"go", "sql"},
+		},
+		{
+			name:  "Duplicates dropped",
+			input: "go GO go, sql go",
+			want:  []string{"go", "sql"},
+		},
+		{
+			name:  "Invalid names kept",
+			input: "c++ .net fü!",
+			want:  []string{"c++", ".net", "fü!"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTags(tt.input)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippetFilename(t *testing.T) {
+	tests := []struct {
+		name    string
+		snippet *models.Snippet
+		want    string
+	}{
+		{
+			name:    "Named main file",
+			snippet: &models.Snippet{Title: "My query", Filename: "schema.sql", Language: "go"},
+			want:    "schema.sql",
+		},
+		{
+			name:    "Title and language",
+			snippet: &models.Snippet{Title: "My Query", Language: "sql"},
+			want:    "my-query.sql",
+		},
+		{
+			name:    "Plain text",
+			snippet: &models.Snippet{Title: "Notes"},
+			want:    "notes.txt",
+		},
+		{
+			name:    "Unknown language",
+			snippet: &models.Snippet{Title: "Notes", Language: "cobol"},
+			want:    "notes.txt",
+		},
+		{
+			name:    "Markdown",
+			snippet: &models.Snippet{Title: "Read me", Language: "go", Format: models.FormatMarkdown},
+			want:    "read-me.md",
+		},
+		{
+			name:    "Punctuation",
+			snippet: &models.Snippet{Title: "  Hello, World! (v2.0)  ", Language: "python"},
+			want:    "hello-world-v2-0.py",
+		},
+		{
+			name:    "No usable characters",
+			snippet: &models.Snippet{ShortID: "aB3dE5gH7j", Title: "日本語 !!!"},
+			want:    "snippet-aB3dE5gH7j.txt",
+		},
+		{
+			name:    "Long title",
+			snippet: &models.Snippet{Title: strings.Repeat("word ", 20), Language: "go"},
+			want:    strings.TrimSuffix(strings.Repeat("word-", 10), "-") + ".go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := snippetFilename(tt.snippet); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
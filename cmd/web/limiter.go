@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// An attemptLimiter limits attempts at something (like guessing a snippet's
+// password) per key, allowing at most max attempts within the window. Every
+// attempt counts until it succeeds and the caller calls Reset. It's safe for
+// concurrent use.
+type attemptLimiter struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	attempts map[string][]time.Time
+	swept    time.Time        // When the whole map was last swept.
+	now      func() time.Time // Tests replace this to control the clock.
+}
+
+func newAttemptLimiter(max int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		max:      max,
+		window:   window,
+		attempts: make(map[string][]time.Time),
+		now:      time.Now,
+	}
+}
+
+// recent returns the attempts for key which are still inside the window,
+// forgetting about the older ones. The caller must hold the mutex.
+func (l *attemptLimiter) recent(key string, now time.Time) []time.Time {
+	attempts := l.attempts[key]
+
+	i := 0
+	for i < len(attempts) && now.Sub(attempts[i]) >= l.window {
+		i++
+	}
+	attempts = attempts[i:]
+
+	if len(attempts) == 0 {
+		delete(l.attempts, key)
+	} else {
+		l.attempts[key] = attempts
+	}
+
+	return attempts
+}
+
+// Acquire reports whether another attempt is allowed for key, and if so
+// counts it straight away. Checking and counting under the same lock means
+// that parallel requests can't all get in before the first one fails.
+func (l *attemptLimiter) Acquire(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	// Keys which are never tried again would otherwise stay in the map for
+	// ever, so once per window sweep out everything which has aged out.
+	if now.Sub(l.swept) >= l.window {
+		for k := range l.attempts {
+			l.recent(k, now)
+		}
+		l.swept = now
+	}
+
+	attempts := l.recent(key, now)
+	if len(attempts) >= l.max {
+		return false
+	}
+	l.attempts[key] = append(attempts, now)
+
+	return true
+}
+
+// Reset forgets the attempts for key, for example after a successful one.
+func (l *attemptLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestLimiter returns an attemptLimiter whose clock only moves when the
+// returned function is called to advance it.
+func newTestLimiter(max int, window time.Duration) (*attemptLimiter, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	l := newAttemptLimiter(max, window)
+	l.now = func() time.Time { return now }
+
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestAttemptLimiter(t *testing.T) {
+	l, advance := newTestLimiter(3, time.Minute)
+
+	for i := 1; i <= 3; i++ {
+		if !l.Acquire("a") {
+			t.Fatalf("attempt %d was refused", i)
+		}
+		advance(10 * time.Second)
+	}
+
+	if l.Acquire("a") {
+		t.Error("a fourth attempt within the window was allowed")
+	}
+
+	// Other keys are counted separately.
+	if !l.Acquire("b") {
+		t.Error("a different key was refused")
+	}
+
+	// The first attempt was at 0s, so it leaves the window at 60s. It's now
+	// 30s, so wait another 30s.
+	advance(29 * time.Second)
+	if l.Acquire("a") {
+		t.Error("an attempt was allowed before any attempt left the window")
+	}
+	advance(time.Second)
+	if !l.Acquire("a") {
+		t.Error("an attempt was refused after the oldest attempt left the window")
+	}
+
+	// But only one attempt, as the other two are still recent.
+	if l.Acquire("a") {
+		t.Error("an attempt was allowed with three recent attempts")
+	}
+}
+
+func TestAttemptLimiterReset(t *testing.T) {
+	l, _ := newTestLimiter(2, time.Minute)
+
+	l.Acquire("a")
+	l.Acquire("a")
+	if l.Acquire("a") {
+		t.Fatal("an attempt was allowed after two attempts")
+	}
+
+	l.Reset("a")
+	if !l.Acquire("a") {
+		t.Error("an attempt was refused after a reset")
+	}
+}
+
+func TestAttemptLimiterConcurrent(t *testing.T) {
+	l := newAttemptLimiter(5, time.Minute)
+
+	var admitted atomic.Int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if l.Acquire("a") {
+				admitted.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if got := admitted.Load(); got != 5 {
+		t.Errorf("got %d attempts admitted; want 5", got)
+	}
+}
+
+func TestAttemptLimiterForgetsOldKeys(t *testing.T) {
+	l, advance := newTestLimiter(5, time.Minute)
+
+	for i := 0; i < 1000; i++ {
+		l.Acquire(fmt.Sprint(i))
+	}
+	if len(l.attempts) != 1000 {
+		t.Fatalf("got %d keys; want 1000", len(l.attempts))
+	}
+
+	// Within the window nothing is swept, even though the first attempts are
+	// old by now.
+	advance(59 * time.Second)
+	l.Acquire("new")
+	if len(l.attempts) != 1001 {
+		t.Errorf("got %d keys before the window passed; want 1001", len(l.attempts))
+	}
+
+	// Once a window has passed since the last sweep, everything which has
+	// aged out is swept away.
+	advance(time.Second)
+	l.Acquire("newer")
+	if len(l.attempts) != 2 {
+		t.Errorf("got %d keys after the window passed; want 2", len(l.attempts))
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql"
 	"flag"
@@ -8,6 +9,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	// Import the models package that we just created. You need to prefix this with
@@ -15,6 +19,7 @@ import (
 	// a Module) so that the import statement looks like this:
 	// "{your-module-path}/internal/models". If you can't remember what module path you
 	// used, you can find it at the top of the go.mod file.
+	"github.com/Praveen005/snippetbox/internal/blob"
 	"github.com/Praveen005/snippetbox/internal/models"
 
 	"github.com/alexedwards/scs/mysqlstore"
@@ -29,9 +34,18 @@ type application struct{
 	errorLog 		*log.Logger
 	snippets 		*models.SnippetModel
 	users 			*models.UserModel
+	stars 			*models.StarModel
+	comments 		*models.CommentModel
+	collections		*models.CollectionModel
 	templateCache	map[string]*template.Template
 	formDecoder 	*form.Decoder
 	sessionManager  *scs.SessionManager
+	pageSize		int
+	unlockLimiter	*attemptLimiter
+	blobs			*blob.Store
+	attachmentsMu	sync.RWMutex	// See deleteUnusedAttachments().
+	maxAttachmentSize int64
+	views			*viewCounter
 }
 
 func main(){
@@ -39,6 +53,21 @@ func main(){
 	addr := flag.String("addr", ":4000", "HTTP Network Address")
 	// Define a new command-line flag for the MySQL DSN(data source name: depend on which database and driver you’re using.) string.
 	dsn := flag.String("dsn", "web:p123@/snippetbox?parseTime=true", "MYSQL data source name")
+	// The number of snippets shown per page, unless the request asks for a
+	// different size.
+	pageSize := flag.Int("page-size", 10, "Default number of snippets per page")
+	// How often to purge expired snippets from the database (0 turns this off),
+	// how long to keep them after they expire, and how many rows to delete
+	// with each statement. Keeping them for a week by default means owners
+	// can still find them on their dashboard for a while.
+	reapInterval := flag.Duration("reap-interval", 10*time.Minute, "How often to delete expired snippets (0 to disable)")
+	reapGrace := flag.Duration("reap-grace", 7*24*time.Hour, "How long to keep snippets after they expire")
+	reapBatch := flag.Int("reap-batch", 100, "Maximum number of expired snippets to delete at once")
+	// Where to store uploaded attachments, and how big each one can be.
+	uploadDir := flag.String("upload-dir", "./uploads", "Directory to store attachments in")
+	maxAttachmentSize := flag.Int64("max-attachment-size", 5<<20, "Maximum size of an attachment in bytes")
+	// How often to write the buffered snippet view counts to the database.
+	viewsInterval := flag.Duration("views-flush-interval", 10*time.Second, "How often to save snippet view counts")
 	flag.Parse()	
 
 
@@ -59,6 +88,13 @@ func main(){
 	defer db.Close()
 
 
+	// Make sure the attachments directory exists before we start.
+	err = os.MkdirAll(*uploadDir, 0o750)
+	if err != nil{
+		errorLog.Fatal(err)
+	}
+
+
 	// Initializa a new template cache
 	templateCache, err  := newTemplateCache()
 	if err != nil{
@@ -93,9 +129,18 @@ func main(){
 		infoLog: infoLog,
 		snippets: &models.SnippetModel{DB: db},
 		users: &models.UserModel{DB: db},
+		stars: &models.StarModel{DB: db},
+		comments: &models.CommentModel{DB: db},
+		collections: &models.CollectionModel{DB: db},
 		templateCache: templateCache,
 		formDecoder: formDecoder,
 		sessionManager: sessionManager,
+		pageSize: *pageSize,
+		// Allow 5 wrong passwords per snippet and client every 15 minutes.
+		unlockLimiter: newAttemptLimiter(5, 15*time.Minute),
+		blobs: &blob.Store{Dir: *uploadDir},
+		maxAttachmentSize: *maxAttachmentSize,
+		views: newViewCounter(),
 	}
 
 
@@ -141,13 +186,86 @@ func main(){
 		WriteTimeout: 10 *time.Second,
 	}
 
+	if *pageSize < 1 || *pageSize > maxPageSize {
+		errorLog.Fatalf("-page-size must be between 1 and %d", maxPageSize)
+	}
+	if *reapBatch < 1 {
+		errorLog.Fatal("-reap-batch must be at least 1")
+	}
+	if *reapGrace < 0 {
+		errorLog.Fatal("-reap-grace cannot be negative")
+	}
+	if *viewsInterval <= 0 {
+		errorLog.Fatal("-views-flush-interval must be positive")
+	}
+
+	// Create a context which is cancelled when the process is asked to stop
+	// with Ctrl+C or a SIGTERM. Everything running in the background watches
+	// it, and the WaitGroup lets us wait for them all to finish.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var wg sync.WaitGroup
+
+	if *reapInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			app.reapExpiredSnippets(ctx, *reapInterval, *reapGrace, *reapBatch)
+		}()
+	}
+
+	// The view counts get their own context, which is only cancelled once the
+	// server has stopped handling requests. That way the final flush includes
+	// the views from requests which were still in flight during shutdown.
+	viewsCtx, stopViews := context.WithCancel(context.Background())
+	defer stopViews()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.flushViewsEvery(viewsCtx, *viewsInterval)
+	}()
+
 	infoLog.Printf("Starting server on %s", *addr)
 
 	// Use the ListenAndServeTLS() method to start the HTTPS server. We
 	// pass in the paths to the TLS certificate and corresponding private key as
-	// the two parameters.
-	err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
-	errorLog.Fatal(err)
+	// the two parameters. It runs in its own goroutine, so that we can wait for
+	// a shutdown signal at the same time.
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
+	}()
+
+	select {
+	case err = <-serverErr:
+		// The server couldn't start (or died), so stop everything else too.
+		stop()
+		stopViews()
+		wg.Wait()
+		errorLog.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	// Stop listening for signals, so that a second Ctrl+C kills the process
+	// straight away if shutting down takes too long.
+	stop()
+	infoLog.Print("Shutting down server")
+
+	// Give in-flight requests up to 10 seconds to complete, then wait for
+	// the background goroutines to finish.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = srv.Shutdown(shutdownCtx)
+	if err != nil {
+		errorLog.Print(err)
+	}
+
+	stopViews()
+	wg.Wait()
+	infoLog.Print("Server stopped")
 }
 
 // The openDB() function wraps sql.Open() and returns a sql.DB connection pool
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/justinas/nosurf"
 )
@@ -20,6 +22,90 @@ func secureHeaders(next http.Handler) http.Handler{
 	})
 }
 
+// extendDeadlines returns middleware which gives the request d to finish
+// reading its body and writing its response, instead of the server's usual
+// ReadTimeout and WriteTimeout. Those are kept short for most requests, but
+// are far too short for a large upload over a slow connection.
+func (app *application) extendDeadlines(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline := time.Now().Add(d)
+
+			rc := http.NewResponseController(w)
+			err := rc.SetReadDeadline(deadline)
+			if err == nil {
+				err = rc.SetWriteDeadline(deadline)
+			}
+			if err != nil {
+				app.serverError(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitRequestBody returns middleware which refuses request bodies bigger than
+// n bytes. It has to come before noSurf in the chain, because noSurf reads the
+// whole form to find the CSRF token.
+func (app *application) limitRequestBody(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// If the client tells us up front that the body is too big,
+			// refuse it straight away. Otherwise MaxBytesReader() makes
+			// reading the body fail once it goes over the limit.
+			if r.ContentLength > n {
+				w.Header().Set("Connection", "close")
+				app.clientError(w, http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// parseBody returns middleware which parses a URL-encoded or multipart form
+// body, keeping at most maxMemory bytes of uploaded files in memory and
+// writing the rest to temporary files. It has to come before noSurf, which
+// would otherwise parse the body itself and keep up to 32MB of it in memory.
+// A body which goes over the limitRequestBody() limit gets a 413 response.
+func (app *application) parseBody(maxMemory int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// ParseForm() reads URL-encoded bodies and leaves multipart ones
+			// alone. ParseMultipartForm() would call it too, but then return
+			// ErrNotMultipart for a URL-encoded body instead of its error.
+			err := r.ParseForm()
+			if err == nil {
+				err = r.ParseMultipartForm(maxMemory)
+				if errors.Is(err, http.ErrNotMultipart) {
+					err = nil
+				}
+			}
+			if err != nil {
+				var maxBytesError *http.MaxBytesError
+				if errors.As(err, &maxBytesError) {
+					app.clientError(w, http.StatusRequestEntityTooLarge)
+				} else {
+					app.clientError(w, http.StatusBadRequest)
+				}
+				return
+			}
+
+			// The server only removes the temporary files of the request it
+			// passed in, and r may be a copy of that by now.
+			if r.MultipartForm != nil {
+				defer r.MultipartForm.RemoveAll()
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (app *application) logRequest(next http.Handler) http.Handler{
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		app.infoLog.Printf("%s - %s %s %s", r.RemoteAddr, r.Proto, r.Method, r.URL.RequestURI())
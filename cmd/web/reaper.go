@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// reapExpiredSnippets deletes expired snippets from the database every
+// interval, until ctx is cancelled. Snippets are only deleted once they've
+// been expired for longer than grace, which gives owners a chance to still see
+// them on their dashboard for a while. Each run deletes batch rows at a time,
+// and carries on until there's nothing left to delete. Attachment files which
+// no remaining snippet uses are deleted from disk too.
+func (app *application) reapExpiredSnippets(ctx context.Context, interval, grace time.Duration, batch int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		total := 0
+		for ctx.Err() == nil {
+			n, sums, err := app.snippets.DeleteExpired(grace, batch)
+			if err != nil {
+				app.errorLog.Print(err)
+				break
+			}
+			app.deleteUnusedAttachments(sums)
+
+			total += n
+			if n < batch {
+				break
+			}
+		}
+
+		if total > 0 {
+			app.infoLog.Printf("Deleted %d expired snippets", total)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package main
 import(
 	"net/http"
 
+	"github.com/Praveen005/snippetbox/ui"
+
 	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
 )
@@ -37,6 +39,14 @@ func(app *application) routes() http.Handler{
 	// http.FileServer() function to create the file server handler.
 	fileServer := http.FileServer(http.FS(ui.Files))
 
+	// Our static files are contained in the "static" folder of the ui.Files
+	// embedded filesystem. So, for example, our CSS stylesheet is located at
+	// "static/css/main.css". This means that we no longer need to strip the
+	// prefix from the request URL -- any requests that start with /static/ can
+	// just be passed directly to the file server and the corresponding static
+	// file will be served (so long as it exists).
+	router.Handler(http.MethodGet, "/static/*filepath", fileServer)
+
 
 
 
@@ -50,7 +60,23 @@ func(app *application) routes() http.Handler{
 
 
 	router.Handler(http.MethodGet, "/", dynamic.ThenFunc(app.home))
+	router.Handler(http.MethodGet, "/snippets", dynamic.ThenFunc(app.snippetList))
+	// Attachments are served from outside /static/, because unlike static
+	// files they're subject to the same access checks as their snippet.
+	router.Handler(http.MethodGet, "/attachments/:id/:sha", dynamic.ThenFunc(app.snippetAttachment))
 	router.Handler(http.MethodGet, "/snippet/view/:id", dynamic.ThenFunc(app.snippetView))
+	router.Handler(http.MethodGet, "/snippet/view/:id/history", dynamic.ThenFunc(app.snippetHistory))
+	router.Handler(http.MethodGet, "/snippet/view/:id/history/:rev", dynamic.ThenFunc(app.snippetRevision))
+	router.Handler(http.MethodGet, "/snippet/raw/:id", dynamic.ThenFunc(app.snippetRaw))
+	router.Handler(http.MethodGet, "/snippet/raw/:id/:file", dynamic.ThenFunc(app.snippetRawFile))
+	router.Handler(http.MethodGet, "/snippet/download/:id", dynamic.ThenFunc(app.snippetDownload))
+	router.Handler(http.MethodPost, "/snippet/unlock/:id", dynamic.ThenFunc(app.snippetUnlockPost))
+	router.Handler(http.MethodPost, "/snippet/reveal/:id", dynamic.ThenFunc(app.snippetRevealPost))
+	router.Handler(http.MethodGet, "/snippet/diff", dynamic.ThenFunc(app.snippetDiff))
+	router.Handler(http.MethodGet, "/snippet/search", dynamic.ThenFunc(app.snippetSearch))
+	router.Handler(http.MethodGet, "/snippet/hash/:sha", dynamic.ThenFunc(app.snippetHash))
+	router.Handler(http.MethodGet, "/tag/:name", dynamic.ThenFunc(app.tagView))
+	router.Handler(http.MethodGet, "/collection/:id", dynamic.ThenFunc(app.collectionView))
 	router.Handler(http.MethodGet, "/user/signup", dynamic.ThenFunc(app.userSignup))
 	router.Handler(http.MethodPost, "/user/signup", dynamic.ThenFunc(app.userSignupPost))
 	router.Handler(http.MethodGet, "/user/login", dynamic.ThenFunc(app.userLogin))
@@ -67,9 +93,46 @@ func(app *application) routes() http.Handler{
 	// the noSurf middleware will also be used on the three routes below too.
 	protected := dynamic.Append(app.requireAuthentication)
 
+	// The routes which take big request bodies get a chain of their own, so
+	// that nothing reads the body until we know who's sending it. Only then
+	// do they get longer than the server's usual timeouts, which bodies that
+	// big need, and the body is limited to n bytes and parsed with at most 1MB
+	// of files in memory. noSurf comes last, so that it finds the body
+	// already parsed instead of parsing it again with its own limits.
+	bigBody := func(n int64) alice.Chain {
+		return alice.New(app.sessionManager.LoadAndSave, app.authenticate, app.requireAuthentication,
+			app.extendDeadlines(uploadTimeout), app.limitRequestBody(n), app.parseBody(1<<20), noSurf)
+	}
+
+	// The create form can upload attachments, so its body is limited to
+	// enough for the most attachments we allow and the biggest snippet, plus
+	// 1MB for everything else.
+	upload := bigBody(maxAttachments*app.maxAttachmentSize + maxContentSize + 1<<20)
+
+	// The edit form is URL-encoded, which can make the content up to three
+	// times bigger, so allow for that. Without a limit of our own, Go would
+	// refuse any URL-encoded body over 10MB.
+	edit := bigBody(3*maxContentSize + 1<<20)
+
 	router.Handler(http.MethodGet, "/snippet/create", protected.ThenFunc(app.snippetCreate))
-	router.Handler(http.MethodPost, "/snippet/create", protected.ThenFunc(app.snippetCreatePost))
+	router.Handler(http.MethodPost, "/snippet/create", upload.ThenFunc(app.snippetCreatePost))
+	router.Handler(http.MethodGet, "/snippet/fork/:id", protected.ThenFunc(app.snippetFork))
+	router.Handler(http.MethodGet, "/snippet/edit/:id", protected.ThenFunc(app.snippetEdit))
+	router.Handler(http.MethodPost, "/snippet/edit/:id", edit.ThenFunc(app.snippetEditPost))
+	router.Handler(http.MethodPost, "/snippet/view/:id/history/:rev/restore", protected.ThenFunc(app.snippetRestorePost))
+	router.Handler(http.MethodPost, "/snippet/view/:id/comments", protected.ThenFunc(app.snippetCommentPost))
+	router.Handler(http.MethodPost, "/snippet/view/:id/comments/:comment/delete", protected.ThenFunc(app.snippetCommentDeletePost))
 	router.Handler(http.MethodPost, "/user/logout", protected.ThenFunc(app.userLogoutPost))
+	router.Handler(http.MethodGet, "/user/snippets", protected.ThenFunc(app.userSnippets))
+	router.Handler(http.MethodGet, "/user/stars", protected.ThenFunc(app.userStars))
+	router.Handler(http.MethodPost, "/snippet/star/:id", protected.ThenFunc(app.snippetStarPost))
+	router.Handler(http.MethodPost, "/snippet/unstar/:id", protected.ThenFunc(app.snippetUnstarPost))
+	router.Handler(http.MethodGet, "/user/collections", protected.ThenFunc(app.userCollections))
+	router.Handler(http.MethodPost, "/user/collections", protected.ThenFunc(app.userCollectionsPost))
+	router.Handler(http.MethodPost, "/collection/:id/add", protected.ThenFunc(app.collectionAddPost))
+	router.Handler(http.MethodPost, "/collection/:id/remove/:snippet", protected.ThenFunc(app.collectionRemovePost))
+	router.Handler(http.MethodPost, "/collection/:id/move/:snippet", protected.ThenFunc(app.collectionMovePost))
+	router.Handler(http.MethodPost, "/collection/:id/delete", protected.ThenFunc(app.collectionDeletePost))
 
 
 	// Using justinas/alice package to chain middleware
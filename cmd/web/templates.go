@@ -1,11 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"html/template"
 	"path/filepath"
+	"slices"
 	"time"
 
+	"github.com/Praveen005/snippetbox/internal/diff"
+	"github.com/Praveen005/snippetbox/internal/highlight"
 	"github.com/Praveen005/snippetbox/internal/models"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
 )
 
 // Add a Form field with the type "any".
@@ -20,21 +29,152 @@ type templateData struct{
 	Flash 			string // Add a Flash field to the templateData struct.
 	IsAuthenticated bool   // Add an IsAuthenticated field to the templateData struct.
 	CSRFToken 		string // Add a CSRFToken field.
+	AuthenticatedUserID int // ID of the logged-in user, or 0.
+	Revision		*models.Revision
+	Revisions		[]*models.Revision
+	Diff			*snippetDiff
+	SearchResults	[]*models.SearchResult
+	Pagination		*models.Pagination
+	Tag				string
+	ForkedFrom		*models.Snippet
+	Forks			[]*models.Snippet
+	Starred			bool
+	Comments		[]*models.Comment
+	Collection		*models.Collection
+	Collections		[]*models.Collection
+	Entries			[]*collectionEntry
+	Duplicate		*models.Snippet
+	Hash			string
+}
+
+// A collectionEntry is one of the snippets shown on a collection's page.
+// Password-protected snippets which the visitor hasn't unlocked are Locked,
+// and only linked to rather than shown.
+type collectionEntry struct {
+	Snippet		*models.Snippet
+	Locked		bool
+}
+
+// A snippetDiff holds a comparison between two texts, laid out both as a
+// unified diff and as side-by-side rows, for rendering by diff.tmpl.
+type snippetDiff struct {
+	Old			diffSide
+	New			diffSide
+	Lines		[]diff.Line
+	Rows		[]diff.Row
+	TooLarge	bool	// The texts were too long or too different to compare.
+}
+
+// A diffSide describes one of the two texts being compared, so the template
+// can label and link to it.
+type diffSide struct {
+	Label		string
+	Title		string
+	URL			string
 }
 
 
 // Create a humanDate function which returns a nicely formatted string
 // representation of a time.Time object.
 func humanDate(t time.Time) string {
+    // Return the empty string if time has the zero value, like the expiry
+    // time of a snippet which never expires.
+    if t.IsZero() {
+        return ""
+    }
+
     // Format the time in the desired format
     return t.Format("02 Jan 2006 at 15:04")
 }
 
+// Create a byteSize function which formats a number of bytes in a
+// human-readable way, like "1.5 MB".
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d bytes", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Create a pageSizes function which returns the page sizes offered on the
+// /snippets page, including the current one if it isn't a standard choice
+// (for example, when it came from the -page-size flag).
+func pageSizes(current int) []int {
+	sizes := []int{10, 25, 50, 100}
+	if !slices.Contains(sizes, current) {
+		sizes = append(sizes, current)
+		slices.Sort(sizes)
+	}
+	return sizes
+}
+
+// Create a highlightCode function (registered as "highlight") which renders
+// code as syntax highlighted HTML on the server, so that no JavaScript is
+// needed in the browser. Unknown languages are shown as plain text.
+func highlightCode(code, language string) (template.HTML, error) {
+	return highlight.HTML(code, language)
+}
+
+// The Markdown renderer supports GitHub Flavored Markdown (tables, task lists,
+// strikethrough and autolinks), which is what most of our runbooks are written
+// in. It leaves out any raw HTML in the source by default.
+var markdownRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// Even so, we never trust the renderer's output on its own. The UGC policy
+// only allows the elements and attributes that Markdown can produce, and
+// strips scripts, event handler attributes and javascript: URLs.
+var markdownPolicy = bluemonday.UGCPolicy()
+
+// Create a markdown function which renders Markdown source to sanitized HTML.
+func markdown(source string) (template.HTML, error) {
+	var buf bytes.Buffer
+
+	err := markdownRenderer.Convert([]byte(source), &buf)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(markdownPolicy.SanitizeBytes(buf.Bytes())), nil
+}
+
+// Create a languageLabel function which returns the human-readable name of a
+// language, like "Go" for "go".
+func languageLabel(language string) string {
+	return highlight.Lookup(language).Label
+}
+
+// Create a languages function which returns the languages offered on the
+// create form.
+func languages() []highlight.Language {
+	return highlight.Languages
+}
+
+// Create a listExpiryPresets function (registered as "expiryPresets") which
+// returns the expiry presets offered on the create form.
+func listExpiryPresets() []expiryPreset {
+	return expiryPresets
+}
+
 // Initialize a template.FuncMap object and store it in a global variable. This is
 // essentially a string-keyed map which acts as a lookup between the names of our
 // custom template functions and the functions themselves.
 var functions = template.FuncMap{
 	"humanDate": humanDate,
+	"byteSize": byteSize,
+	"pageSizes": pageSizes,
+	"highlight": highlightCode,
+	"markdown": markdown,
+	"languageLabel": languageLabel,
+	"languages": languages,
+	"expiryPresets": listExpiryPresets,
 }
 
 
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A viewCounter buffers snippet view counts in memory, so that viewing a
+// snippet doesn't have to wait for an UPDATE. The counts are written to the
+// database now and then by flushViews(). It's safe for concurrent use.
+type viewCounter struct {
+	mu     sync.Mutex
+	counts map[int]int
+}
+
+func newViewCounter() *viewCounter {
+	return &viewCounter{counts: make(map[int]int)}
+}
+
+// Add records n views of the snippet with the given ID.
+func (c *viewCounter) Add(id, n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts[id] += n
+}
+
+// take returns the buffered counts and starts again with an empty buffer.
+func (c *viewCounter) take() map[int]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := c.counts
+	c.counts = make(map[int]int)
+	return counts
+}
+
+// flushViews writes the buffered view counts to the database. If that fails
+// part of the way through, the counts which weren't written are put back into
+// the buffer, to be tried again next time.
+func (app *application) flushViews() {
+	counts := app.views.take()
+	if len(counts) == 0 {
+		return
+	}
+
+	unapplied, err := app.snippets.AddViews(counts)
+	if err != nil {
+		app.errorLog.Print(err)
+		for id, n := range unapplied {
+			app.views.Add(id, n)
+		}
+	}
+}
+
+// flushViewsEvery flushes the buffered view counts every interval until ctx
+// is cancelled, and then one final time so that no views are lost when the
+// server shuts down.
+func (app *application) flushViewsEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			app.flushViews()
+			return
+		case <-ticker.C:
+			app.flushViews()
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Package blob stores files on the local disk, named after the hex-encoded
+// SHA-256 hash of their content. Storing the same content twice only keeps one
+// copy, and a file's name can't be influenced by whoever uploaded it.
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrInvalidSum is returned by Open and Delete for anything which isn't a hex-encoded
+// SHA-256 hash, so it can never be used to reach outside the store.
+var ErrInvalidSum = errors.New("blob: invalid SHA-256 sum")
+
+var sumRx = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// A Store keeps files in Dir. Each file lives in a subdirectory named after
+// the first two characters of its hash, so that no single directory gets too
+// big.
+type Store struct {
+	Dir string
+}
+
+// path returns where the file with the given hash is stored.
+func (s *Store) path(sum string) string {
+	return filepath.Join(s.Dir, sum[:2], sum)
+}
+
+// Put copies everything from r into the store, and returns the hash and size
+// of the content. The content is written to a temporary file first and only
+// moved into place once it's complete, so a half-written file is never seen.
+func (s *Store) Put(r io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp(s.Dir, "upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	// Removing the temporary file fails harmlessly once it has been renamed.
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		return "", 0, err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return "", 0, err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	dst := s.path(sum)
+
+	err = os.MkdirAll(filepath.Dir(dst), 0o750)
+	if err != nil {
+		return "", 0, err
+	}
+
+	err = os.Rename(tmp.Name(), dst)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return sum, size, nil
+}
+
+// Open opens the file with the given hash for reading.
+func (s *Store) Open(sum string) (*os.File, error) {
+	if !sumRx.MatchString(sum) {
+		return nil, ErrInvalidSum
+	}
+	return os.Open(s.path(sum))
+}
+
+// Delete removes the file with the given hash. Deleting a file which isn't
+// there is not an error. The store doesn't know what refers to its files, so
+// it's up to the caller to check that nothing needs this one any more.
+func (s *Store) Delete(sum string) error {
+	if !sumRx.MatchString(sum) {
+		return ErrInvalidSum
+	}
+
+	err := os.Remove(s.path(sum))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
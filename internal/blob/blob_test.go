@@ -0,0 +1,66 @@
+package blob
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestPutOpenDelete(t *testing.T) {
+	store := &Store{Dir: t.TempDir()}
+
+	sum, size, err := store.Put(strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"; sum != want {
+		t.Errorf("got sum %q; want %q", sum, want)
+	}
+	if size != 5 {
+		t.Errorf("got size %d; want 5", size)
+	}
+
+	f, err := store.Open(sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "hello" {
+		t.Errorf("got content %q; want %q", content, "hello")
+	}
+
+	err = store.Delete(sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Open(sum)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v opening a deleted file; want fs.ErrNotExist", err)
+	}
+
+	// Deleting it again is fine.
+	err = store.Delete(sum)
+	if err != nil {
+		t.Errorf("got %v deleting a missing file; want nil", err)
+	}
+}
+
+func TestInvalidSum(t *testing.T) {
+	store := &Store{Dir: t.TempDir()}
+
+	for _, sum := range []string{"", "../../etc/passwd", strings.Repeat("A", 64), strings.Repeat("a", 63)} {
+		if _, err := store.Open(sum); !errors.Is(err, ErrInvalidSum) {
+			t.Errorf("Open(%q): got %v; want ErrInvalidSum", sum, err)
+		}
+		if err := store.Delete(sum); !errors.Is(err, ErrInvalidSum) {
+			t.Errorf("Delete(%q): got %v; want ErrInvalidSum", sum, err)
+		}
+	}
+}
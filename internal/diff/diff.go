@@ -0,0 +1,217 @@
+package diff
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrTooDifferent is returned by Lines when turning one text into the other
+// takes more line edits than the caller allowed.
+var ErrTooDifferent = errors.New("diff: texts are too different")
+
+// Op describes what happened to a line when going from the old text to the
+// new text.
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// String returns a lower-case name for the operation, which the templates use
+// as part of a CSS class name.
+func (op Op) String() string {
+	switch op {
+	case Insert:
+		return "insert"
+	case Delete:
+		return "delete"
+	default:
+		return "equal"
+	}
+}
+
+// Prefix returns the character which marks the operation at the start of a
+// line in a unified diff.
+func (op Op) Prefix() string {
+	switch op {
+	case Insert:
+		return "+"
+	case Delete:
+		return "-"
+	default:
+		return " "
+	}
+}
+
+// Line is a single line of a unified diff. OldNumber and NewNumber are the
+// 1-based line numbers in the old and new text, and are 0 when the line
+// doesn't appear on that side.
+type Line struct {
+	Op        Op
+	Text      string
+	OldNumber int
+	NewNumber int
+}
+
+// Row is a single row of a side-by-side diff. Either side is nil when there is
+// no corresponding line, for example when a line was only added.
+type Row struct {
+	Left  *Line
+	Right *Line
+}
+
+// splitLines splits text into lines, ignoring the difference between Windows
+// and Unix line endings and any trailing newline.
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// Lines returns a unified line diff which turns a into b. It uses Myers'
+// O(ND) algorithm, so the result is a shortest edit script and runs quickly
+// when the two texts are similar.
+//
+// The time taken grows with the number of edits D, and the memory with D², so
+// Lines gives up with ErrTooDifferent once more than maxEdits lines would have
+// to be inserted or deleted.
+func Lines(a, b string, maxEdits int) ([]Line, error) {
+	x, y := splitLines(a), splitLines(b)
+	n, m := len(x), len(y)
+
+	limit := n + m
+	if maxEdits < limit {
+		limit = maxEdits
+	}
+	offset := limit + 1
+
+	// v[offset+k] holds the furthest x reached on diagonal k. Walking back
+	// through round d afterwards only needs the diagonals -d+1 to d-1 as they
+	// were at the start of that round, so that window of v is all we keep.
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	found := false
+
+search:
+	for d := 0; d <= limit; d++ {
+		var window []int
+		if d > 0 {
+			window = append([]int(nil), v[offset-d+1:offset+d]...)
+		}
+		trace = append(trace, window)
+
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				i = v[offset+k+1] // move down: insert a line from b
+			} else {
+				i = v[offset+k-1] + 1 // move right: delete a line from a
+			}
+			j := i - k
+
+			// Follow the diagonal for as long as the lines match.
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+			v[offset+k] = i
+
+			if i >= n && j >= m {
+				found = true
+				break search
+			}
+		}
+	}
+
+	if !found {
+		return nil, ErrTooDifferent
+	}
+
+	// Walk backwards from the end of both texts to the start, emitting lines
+	// in reverse order.
+	var lines []Line
+	i, j := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		k := i - j
+
+		// Round 0 starts from the top-left corner, so there's nothing to
+		// look up.
+		var prevI, prevJ int
+		if d > 0 {
+			window := trace[d]
+			at := func(k int) int { return window[k+d-1] }
+
+			var prevK int
+			if k == -d || (k != d && at(k-1) < at(k+1)) {
+				prevK = k + 1
+			} else {
+				prevK = k - 1
+			}
+			prevI = at(prevK)
+			prevJ = prevI - prevK
+		}
+
+		for i > prevI && j > prevJ {
+			lines = append(lines, Line{Op: Equal, Text: x[i-1], OldNumber: i, NewNumber: j})
+			i--
+			j--
+		}
+
+		if d > 0 {
+			if i == prevI {
+				lines = append(lines, Line{Op: Insert, Text: y[j-1], NewNumber: j})
+			} else {
+				lines = append(lines, Line{Op: Delete, Text: x[i-1], OldNumber: i})
+			}
+		}
+
+		i, j = prevI, prevJ
+	}
+
+	for l, r := 0, len(lines)-1; l < r; l, r = l+1, r-1 {
+		lines[l], lines[r] = lines[r], lines[l]
+	}
+
+	return lines, nil
+}
+
+// SideBySide arranges the lines of a unified diff into rows, pairing each run
+// of deleted lines with the run of inserted lines that replaced it.
+func SideBySide(lines []Line) []Row {
+	var rows []Row
+	var deleted, inserted []*Line
+
+	flush := func() {
+		for len(deleted) > 0 || len(inserted) > 0 {
+			var row Row
+			if len(deleted) > 0 {
+				row.Left, deleted = deleted[0], deleted[1:]
+			}
+			if len(inserted) > 0 {
+				row.Right, inserted = inserted[0], inserted[1:]
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	for i := range lines {
+		line := &lines[i]
+		switch line.Op {
+		case Delete:
+			deleted = append(deleted, line)
+		case Insert:
+			inserted = append(inserted, line)
+		default:
+			flush()
+			rows = append(rows, Row{Left: line, Right: line})
+		}
+	}
+	flush()
+
+	return rows
+}
@@ -0,0 +1,198 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// render writes a unified diff out as text, one "<prefix><text>" per line,
+// which is much easier to compare than the Line values themselves.
+func render(lines []Line) string {
+	var sb strings.Builder
+	for _, l := range lines {
+		fmt.Fprintf(&sb, "%s%s\n", l.Op.Prefix(), l.Text)
+	}
+	return sb.String()
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "Both empty",
+			want: "",
+		},
+		{
+			name: "Identical",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo\n",
+			want: " one\n two\n",
+		},
+		{
+			name: "Added to empty",
+			b:    "one\ntwo",
+			want: "+one\n+two\n",
+		},
+		{
+			name: "Deleted everything",
+			a:    "one\ntwo",
+			want: "-one\n-two\n",
+		},
+		{
+			name: "Changed line",
+			a:    "one\ntwo\nthree",
+			b:    "one\n2\nthree",
+			want: " one\n-two\n+2\n three\n",
+		},
+		{
+			name: "Inserted and deleted",
+			a:    "a\nb\nc\nd",
+			b:    "a\nc\nd\ne",
+			want: " a\n-b\n c\n d\n+e\n",
+		},
+		{
+			name: "Windows line endings",
+			a:    "one\r\ntwo\r\n",
+			b:    "one\ntwo",
+			want: " one\n two\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := Lines(tt.a, tt.b, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := render(lines); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinesNumbers(t *testing.T) {
+	lines, err := Lines("a\nb\nc", "a\nx\nc", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Line{
+		{Op: Equal, Text: "a", OldNumber: 1, NewNumber: 1},
+		{Op: Delete, Text: "b", OldNumber: 2},
+		{Op: Insert, Text: "x", NewNumber: 2},
+		{Op: Equal, Text: "c", OldNumber: 3, NewNumber: 3},
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("got %+v; want %+v", lines, want)
+	}
+}
+
+func TestLinesMaxEdits(t *testing.T) {
+	// Two edits are needed to turn "a" into "b".
+	_, err := Lines("a", "b", 1)
+	if !errors.Is(err, ErrTooDifferent) {
+		t.Errorf("got %v; want ErrTooDifferent", err)
+	}
+
+	_, err = Lines("a", "b", 2)
+	if err != nil {
+		t.Errorf("got %v; want nil", err)
+	}
+}
+
+// numbered returns n lines of text, each one made unique by prefix.
+func numbered(prefix string, n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "%s %d\n", prefix, i)
+	}
+	return sb.String()
+}
+
+// allocated returns how many bytes f allocates.
+func allocated(f func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func TestLinesLargeInput(t *testing.T) {
+	a := numbered("old", 6000)
+	b := numbered("new", 6000)
+
+	// Completely different texts need 12,000 edits. Giving up after 1,000
+	// should only cost the memory for those 1,000 rounds.
+	var err error
+	bytes := allocated(func() {
+		_, err = Lines(a, b, 1000)
+	})
+	if !errors.Is(err, ErrTooDifferent) {
+		t.Errorf("got %v; want ErrTooDifferent", err)
+	}
+	if bytes > 32<<20 {
+		t.Errorf("allocated %d bytes; want at most %d", bytes, 32<<20)
+	}
+
+	// A few changes in a long text should be cheap.
+	c := strings.Replace(a, "old 3000\n", "changed\n", 1)
+	var lines []Line
+	bytes = allocated(func() {
+		lines, err = Lines(a, c, 1000)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 6001 {
+		t.Errorf("got %d lines; want 6001", len(lines))
+	}
+	if bytes > 8<<20 {
+		t.Errorf("allocated %d bytes; want at most %d", bytes, 8<<20)
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	lines, err := Lines("a\nb\nc\nd", "a\nx\ny\nd", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := SideBySide(lines)
+
+	// Each row is written as "left|right", with "-" for a missing side.
+	side := func(l *Line) string {
+		if l == nil {
+			return "-"
+		}
+		return l.Text
+	}
+	var got []string
+	for _, row := range rows {
+		got = append(got, side(row.Left)+"|"+side(row.Right))
+	}
+
+	want := []string{"a|a", "b|x", "c|y", "d|d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	// A deletion with nothing to replace it leaves the right side empty.
+	lines, err = Lines("a\nb", "a", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows = SideBySide(lines)
+	if len(rows) != 2 || rows[1].Left == nil || rows[1].Right != nil {
+		t.Errorf("got %+v; want a row with only the deleted line", rows)
+	}
+}
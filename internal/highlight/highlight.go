@@ -0,0 +1,108 @@
+package highlight
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// A Language is one of the languages a snippet can be highlighted as. Name is
+// what we store in the database and pass to chroma, Label is what we show to
+// users, and Extension is used when naming downloaded files.
+type Language struct {
+	Name      string
+	Label     string
+	Extension string
+}
+
+// Languages lists the languages offered on the create form. The empty name
+// means plain text, which is also what we fall back to for anything unknown.
+var Languages = []Language{
+	{Name: "", Label: "Plain text", Extension: "txt"},
+	{Name: "bash", Label: "Shell", Extension: "sh"},
+	{Name: "c", Label: "C", Extension: "c"},
+	{Name: "css", Label: "CSS", Extension: "css"},
+	{Name: "docker", Label: "Dockerfile", Extension: "dockerfile"},
+	{Name: "go", Label: "Go", Extension: "go"},
+	{Name: "html", Label: "HTML", Extension: "html"},
+	{Name: "ini", Label: "INI", Extension: "ini"},
+	{Name: "java", Label: "Java", Extension: "java"},
+	{Name: "javascript", Label: "JavaScript", Extension: "js"},
+	{Name: "json", Label: "JSON", Extension: "json"},
+	{Name: "python", Label: "Python", Extension: "py"},
+	{Name: "rust", Label: "Rust", Extension: "rs"},
+	{Name: "sql", Label: "SQL", Extension: "sql"},
+	{Name: "toml", Label: "TOML", Extension: "toml"},
+	{Name: "typescript", Label: "TypeScript", Extension: "ts"},
+	{Name: "yaml", Label: "YAML", Extension: "yaml"},
+}
+
+// Names returns the Name of every supported language, for validating forms.
+func Names() []string {
+	names := make([]string, len(Languages))
+	for i, l := range Languages {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// Lookup returns the supported language with the given name. Unknown names
+// get the plain text language.
+func Lookup(name string) Language {
+	for _, l := range Languages {
+		if l.Name == name {
+			return l
+		}
+	}
+	return Languages[0]
+}
+
+// The formatter emits CSS class names rather than inline style attributes.
+// Our Content-Security-Policy doesn't allow inline styles, so the colours come
+// from the highlight.css stylesheet instead (see WriteCSS).
+var formatter = html.New(html.WithClasses(true), html.TabWidth(4))
+
+// The chroma style which highlight.css was generated from.
+const styleName = "github"
+
+// HTML returns the code as highlighted HTML, wrapped in a <pre> element. Code
+// in a language chroma doesn't know about is escaped and returned unstyled.
+func HTML(code, language string) (template.HTML, error) {
+	var lexer chroma.Lexer
+	if language != "" {
+		lexer = lexers.Get(language)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	// Coalesce merges runs of tokens of the same type, which keeps the HTML
+	// smaller.
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = formatter.Format(&buf, styles.Get(styleName), iterator)
+	if err != nil {
+		return "", err
+	}
+
+	// The formatter escapes the code itself, so it's safe to mark the output
+	// as trusted HTML.
+	return template.HTML(buf.String()), nil
+}
+
+// WriteCSS writes the stylesheet for the classes used by HTML(). It was used
+// to generate ui/static/css/highlight.css, and should be run again if the
+// style or the chroma version changes.
+func WriteCSS(w io.Writer) error {
+	return formatter.WriteCSS(w, styles.Get(styleName))
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// An Attachment is a file uploaded along with a snippet. The file itself is
+// kept on disk, named after its SHA-256 hash; only its details are stored in
+// the database.
+type Attachment struct {
+	SnippetID   int
+	SHA256      string
+	Filename    string
+	ContentType string
+	Size        int64
+	Created     time.Time
+}
+
+// insertAttachments stores the details of a snippet's attachments. It must be
+// called inside the transaction which wrote the snippet.
+func insertAttachments(tx *sql.Tx, snippetID int, attachments []*Attachment) error {
+	stmt := `INSERT INTO attachments (snippet_id, sha256, filename, content_type, size, created)
+	VALUES (?, ?, ?, ?, ?, UTC_TIMESTAMP())`
+
+	for _, a := range attachments {
+		_, err := tx.Exec(stmt, snippetID, a.SHA256, a.Filename, a.ContentType, a.Size)
+		if err != nil {
+			return err
+		}
+		a.SnippetID = snippetID
+	}
+
+	return nil
+}
+
+// LoadAttachments fills in the Attachments field of a snippet, in the order
+// they were uploaded.
+func (m *SnippetModel) LoadAttachments(s *Snippet) error {
+	stmt := `SELECT snippet_id, sha256, filename, content_type, size, created
+	FROM attachments WHERE snippet_id = ? ORDER BY id`
+
+	rows, err := m.DB.Query(stmt, s.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	s.Attachments = []*Attachment{}
+	for rows.Next() {
+		a := &Attachment{}
+		err := rows.Scan(&a.SnippetID, &a.SHA256, &a.Filename, &a.ContentType, &a.Size, &a.Created)
+		if err != nil {
+			return err
+		}
+		s.Attachments = append(s.Attachments, a)
+	}
+
+	return rows.Err()
+}
+
+// GetAttachment returns the attachment with the given hash on a snippet, or
+// ErrNoRecord if the snippet has no such attachment. Looking attachments up
+// through their snippet means that access to them is controlled by the
+// snippet, even when the same file is attached to several snippets.
+func (m *SnippetModel) GetAttachment(snippetID int, sha256 string) (*Attachment, error) {
+	stmt := `SELECT snippet_id, sha256, filename, content_type, size, created
+	FROM attachments WHERE snippet_id = ? AND sha256 = ? ORDER BY id LIMIT 1`
+
+	a := &Attachment{}
+	err := m.DB.QueryRow(stmt, snippetID, sha256).Scan(&a.SnippetID, &a.SHA256, &a.Filename, &a.ContentType, &a.Size, &a.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// UnusedAttachments returns those of the given hashes which no attachment
+// refers to any more, so that their files can be deleted from disk.
+func (m *SnippetModel) UnusedAttachments(sums []string) ([]string, error) {
+	if len(sums) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, len(sums))
+	for i, sum := range sums {
+		args[i] = sum
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+
+	stmt := `SELECT DISTINCT sha256 FROM attachments WHERE sha256 IN (` + placeholders + `)`
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	used := map[string]bool{}
+	for rows.Next() {
+		var sum string
+		err := rows.Scan(&sum)
+		if err != nil {
+			return nil, err
+		}
+		used[sum] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	unused := []string{}
+	for _, sum := range sums {
+		if !used[sum] {
+			unused = append(unused, sum)
+			used[sum] = true // Only list each hash once.
+		}
+	}
+
+	return unused, nil
+}
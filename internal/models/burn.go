@@ -0,0 +1,53 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Burn fetches a live burn-after-reading snippet and deletes it, all inside a
+// single transaction. The SELECT ... FOR UPDATE locks the row, so if two
+// requests try to burn the same snippet at once, the second one waits for the
+// first to commit and then finds nothing: only one reader ever gets the
+// content. Any other caller gets ErrNoRecord.
+//
+// The snippet's revisions, tags and extra files are removed along with it by the ON DELETE
+// CASCADE foreign keys.
+func (m *SnippetModel) Burn(id int) (*Snippet, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+	WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND id = ? AND burn_after_reading
+	FOR UPDATE`
+
+	s, err := scanSnippet(tx.QueryRow(stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	// The extra files of a multi-file snippet are about to be deleted too,
+	// so read them while we still can.
+	err = loadFiles(tx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM snippets WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
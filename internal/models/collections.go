@@ -0,0 +1,205 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// A Collection is a named, ordered list of snippets put together by a user,
+// like an onboarding runbook. Just like snippets, collections are identified
+// in URLs by a random short ID.
+type Collection struct {
+	ID      int
+	ShortID string
+	UserID  int
+	Title   string
+	Created time.Time
+	Size    int // How many snippets are in the collection, including expired ones.
+}
+
+// Define a CollectionModel type which wraps a database connection pool.
+type CollectionModel struct {
+	DB *sql.DB
+}
+
+// collectionColumns lists the columns selected by every query which returns
+// Collection values, in the order that scanCollection() expects them.
+const collectionColumns = `collections.id, collections.short_id, collections.user_id,
+	collections.title, collections.created,
+	(SELECT COUNT(*) FROM collection_snippets WHERE collection_snippets.collection_id = collections.id)`
+
+func scanCollection(row rowScanner) (*Collection, error) {
+	c := &Collection{}
+	err := row.Scan(&c.ID, &c.ShortID, &c.UserID, &c.Title, &c.Created, &c.Size)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Insert creates a new, empty collection owned by userID and returns its
+// short ID.
+func (m *CollectionModel) Insert(userID int, title string) (string, error) {
+	stmt := `INSERT INTO collections (short_id, user_id, title, created)
+	VALUES (?, ?, ?, UTC_TIMESTAMP())`
+
+	// Just like snippets, try again with another short ID if the one we
+	// picked is already taken.
+	for attempt := 1; ; attempt++ {
+		shortID, err := newShortID()
+		if err != nil {
+			return "", err
+		}
+
+		_, err = m.DB.Exec(stmt, shortID, userID, title)
+		if err == nil {
+			return shortID, nil
+		}
+		if !isDuplicateShortID(err, "collections") || attempt == shortIDAttempts {
+			return "", err
+		}
+	}
+}
+
+// Get returns the collection with the given short ID, or ErrNoRecord.
+func (m *CollectionModel) Get(shortID string) (*Collection, error) {
+	stmt := `SELECT ` + collectionColumns + ` FROM collections WHERE short_id = ?`
+
+	c, err := scanCollection(m.DB.QueryRow(stmt, shortID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// ByUser returns the collections owned by userID, sorted by title.
+func (m *CollectionModel) ByUser(userID int) ([]*Collection, error) {
+	stmt := `SELECT ` + collectionColumns + ` FROM collections
+	WHERE user_id = ? ORDER BY title, id`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []*Collection{}
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+// Delete deletes a collection. The snippets in it are left alone.
+func (m *CollectionModel) Delete(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM collections WHERE id = ?`, id)
+	return err
+}
+
+// AddSnippet appends a snippet to the end of a collection. Adding a snippet
+// which is already in the collection does nothing.
+func (m *CollectionModel) AddSnippet(collectionID, snippetID int) error {
+	stmt := `INSERT IGNORE INTO collection_snippets (collection_id, snippet_id, position)
+	SELECT ?, ?, IFNULL(MAX(position), 0) + 1 FROM collection_snippets WHERE collection_id = ?`
+
+	_, err := m.DB.Exec(stmt, collectionID, snippetID, collectionID)
+	return err
+}
+
+// RemoveSnippet takes a snippet out of a collection, if it was in it.
+func (m *CollectionModel) RemoveSnippet(collectionID, snippetID int) error {
+	stmt := `DELETE FROM collection_snippets WHERE collection_id = ? AND snippet_id = ?`
+
+	_, err := m.DB.Exec(stmt, collectionID, snippetID)
+	return err
+}
+
+// MoveSnippet moves a snippet one place up (towards the start) or down a
+// collection, by swapping its position with the neighbouring live snippet.
+// Moving the first snippet up or the last one down does nothing. It returns
+// ErrNoRecord if the snippet isn't in the collection.
+func (m *CollectionModel) MoveSnippet(collectionID, snippetID int, up bool) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the collection's row, so that concurrent moves are applied one
+	// after the other rather than both swapping with the same neighbour.
+	var position int
+	stmt := `SELECT collection_snippets.position
+	FROM collections INNER JOIN collection_snippets ON collection_snippets.collection_id = collections.id
+	WHERE collections.id = ? AND collection_snippets.snippet_id = ?
+	FOR UPDATE`
+	err = tx.QueryRow(stmt, collectionID, snippetID).Scan(&position)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	// Expired snippets aren't shown, so skip over them when looking for the
+	// neighbour. Otherwise some moves would appear to do nothing.
+	stmt = `SELECT collection_snippets.snippet_id, collection_snippets.position
+	FROM collection_snippets INNER JOIN snippets ON snippets.id = collection_snippets.snippet_id
+	WHERE collection_snippets.collection_id = ?
+	AND (snippets.expires IS NULL OR snippets.expires > UTC_TIMESTAMP())`
+	if up {
+		stmt += ` AND collection_snippets.position < ? ORDER BY collection_snippets.position DESC LIMIT 1`
+	} else {
+		stmt += ` AND collection_snippets.position > ? ORDER BY collection_snippets.position LIMIT 1`
+	}
+
+	var neighbourID, neighbourPosition int
+	err = tx.QueryRow(stmt, collectionID, position).Scan(&neighbourID, &neighbourPosition)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	stmt = `UPDATE collection_snippets SET position = ? WHERE collection_id = ? AND snippet_id = ?`
+
+	_, err = tx.Exec(stmt, neighbourPosition, collectionID, snippetID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(stmt, position, collectionID, neighbourID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// InCollection returns the live snippets in a collection, in order. Visibility
+// isn't checked here, as a collection can be viewed by anyone but a private
+// snippet in it only by its owner; use Snippet.VisibleTo() for that.
+func (m *SnippetModel) InCollection(collectionID int) ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+	INNER JOIN collection_snippets ON collection_snippets.snippet_id = snippets.id
+	WHERE collection_snippets.collection_id = ?
+	AND (snippets.expires IS NULL OR snippets.expires > UTC_TIMESTAMP())
+	AND NOT snippets.burn_after_reading
+	ORDER BY collection_snippets.position`
+
+	return m.query(stmt, collectionID)
+}
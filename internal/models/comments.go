@@ -0,0 +1,93 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// A Comment is a remark left on a snippet by a logged-in user. AuthorName is
+// looked up from the users table.
+type Comment struct {
+	ID         int
+	SnippetID  int
+	UserID     int
+	AuthorName string
+	Content    string
+	Created    time.Time
+}
+
+// Define a CommentModel type which wraps a database connection pool.
+type CommentModel struct {
+	DB *sql.DB
+}
+
+// Insert adds a new comment to a snippet and returns its ID.
+func (m *CommentModel) Insert(snippetID, userID int, content string) (int, error) {
+	stmt := `INSERT INTO comments (snippet_id, user_id, content, created)
+	VALUES (?, ?, ?, UTC_TIMESTAMP())`
+
+	result, err := m.DB.Exec(stmt, snippetID, userID, content)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// ForSnippet returns all the comments on a snippet, oldest first.
+func (m *CommentModel) ForSnippet(snippetID int) ([]*Comment, error) {
+	stmt := `SELECT comments.id, comments.snippet_id, comments.user_id, users.name,
+	comments.content, comments.created
+	FROM comments INNER JOIN users ON users.id = comments.user_id
+	WHERE comments.snippet_id = ?
+	ORDER BY comments.id`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*Comment{}
+	for rows.Next() {
+		c := &Comment{}
+		err := rows.Scan(&c.ID, &c.SnippetID, &c.UserID, &c.AuthorName, &c.Content, &c.Created)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// Delete removes a comment from a snippet, but only if it was written by the
+// given user. It returns ErrNoRecord if there's no such comment, so somebody
+// else's comment looks exactly the same as a missing one.
+func (m *CommentModel) Delete(id, snippetID, userID int) error {
+	stmt := `DELETE FROM comments WHERE id = ? AND snippet_id = ? AND user_id = ?`
+
+	result, err := m.DB.Exec(stmt, id, snippetID, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// Content longer than compressThreshold bytes is gzipped before it's stored;
+// small snippets aren't worth it. This goes for snippets, their revisions and
+// their extra files alike. MySQL can't search compressed content, so a plain
+// text copy of each snippet's title and content is kept in the snippet_search
+// table, which has the FULLTEXT index (see setSearchText()).
+const compressThreshold = 16 << 10
+
+// The values of the content_encoding columns. An empty encoding means the
+// content is stored as plain text in the content column. Otherwise the
+// content column is empty, and the compressed content is in content_gz.
+const (
+	encodingNone = ""
+	encodingGzip = "gzip"
+)
+
+// storedContent is how a piece of content (a snippet's, a revision's or a
+// file's) is actually stored: the content_encoding, content and content_gz
+// columns of its row.
+type storedContent struct {
+	Encoding   string
+	Text       string
+	Compressed []byte // nil is stored as NULL.
+}
+
+// encodeContent works out how to store content, compressing it if it's big
+// enough and compression actually makes it smaller.
+func encodeContent(content string) (storedContent, error) {
+	if len(content) <= compressThreshold {
+		return storedContent{Encoding: encodingNone, Text: content}, nil
+	}
+
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	_, err := io.WriteString(zw, content)
+	if err != nil {
+		return storedContent{}, err
+	}
+	err = zw.Close()
+	if err != nil {
+		return storedContent{}, err
+	}
+
+	if buf.Len() >= len(content) {
+		return storedContent{Encoding: encodingNone, Text: content}, nil
+	}
+
+	return storedContent{Encoding: encodingGzip, Compressed: buf.Bytes()}, nil
+}
+
+// decode returns the original content, decompressing it if necessary.
+func (c storedContent) decode() (string, error) {
+	switch c.Encoding {
+	case encodingNone:
+		return c.Text, nil
+	case encodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(c.Compressed))
+		if err != nil {
+			return "", err
+		}
+		defer zr.Close()
+
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			return "", err
+		}
+		return string(content), nil
+	default:
+		return "", fmt.Errorf("models: unknown content encoding %q", c.Encoding)
+	}
+}
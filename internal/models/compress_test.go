@@ -0,0 +1,122 @@
+package models
+
+import (
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestEncodeContent(t *testing.T) {
+	// Random bytes don't compress.
+	random := make([]byte, 3*compressThreshold)
+	_, err := rand.Read(random)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		encoding string
+	}{
+		{
+			name:     "Empty",
+			content:  "",
+			encoding: encodingNone,
+		},
+		{
+			name:     "Small",
+			content:  "SELECT 1;\n",
+			encoding: encodingNone,
+		},
+		{
+			name:     "At the threshold",
+			content:  strings.Repeat("a", compressThreshold),
+			encoding: encodingNone,
+		},
+		{
+			name:     "Over the threshold",
+			content:  strings.Repeat("a", compressThreshold+1),
+			encoding: encodingGzip,
+		},
+		{
+			name:     "Large log",
+			content:  strings.Repeat("2024-01-01 12:00:00 ERROR connection refused\n", 10000),
+			encoding: encodingGzip,
+		},
+		{
+			name:     "Incompressible",
+			content:  string(random),
+			encoding: encodingNone,
+		},
+		{
+			name:     "Multi-byte characters",
+			content:  strings.Repeat("héllo wörld ✓ ", 2000),
+			encoding: encodingGzip,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored, err := encodeContent(tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stored.Encoding != tt.encoding {
+				t.Errorf("got encoding %q; want %q", stored.Encoding, tt.encoding)
+			}
+
+			switch stored.Encoding {
+			case encodingNone:
+				if stored.Compressed != nil {
+					t.Error("plain text content has compressed bytes too")
+				}
+			case encodingGzip:
+				if stored.Text != "" {
+					t.Error("compressed content has plain text too")
+				}
+				if len(stored.Compressed) >= len(tt.content) {
+					t.Errorf("compressed to %d bytes from %d", len(stored.Compressed), len(tt.content))
+				}
+			}
+
+			got, err := stored.decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.content {
+				t.Errorf("content changed in the round trip: got %d bytes; want %d", len(got), len(tt.content))
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored storedContent
+	}{
+		{
+			name:   "Unknown encoding",
+			stored: storedContent{Encoding: "zstd", Compressed: []byte{1, 2, 3}},
+		},
+		{
+			name:   "Not gzip",
+			stored: storedContent{Encoding: encodingGzip, Compressed: []byte("plain text")},
+		},
+		{
+			name:   "Truncated",
+			stored: storedContent{Encoding: encodingGzip, Compressed: []byte{0x1f, 0x8b, 8, 0, 0, 0, 0, 0, 0, 0xff}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.stored.decode()
+			if err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ContentHash returns the SHA-256 hash of a snippet's content, as lower-case
+// hex. It's stored in the content_sha256 column when a snippet is saved, so
+// that identical snippets can be found without comparing their content.
+//
+// For an ordinary snippet that's simply the hash of Content. When s has extra
+// Files, the name and content of every file, the main one included, are
+// hashed instead, so two multi-file snippets only match if all their files do.
+func ContentHash(s *Snippet) string {
+	h := sha256.New()
+
+	if len(s.Files) == 0 {
+		h.Write([]byte(s.Content))
+	} else {
+		// Length prefixes keep the boundaries between names and contents
+		// unambiguous.
+		for _, f := range s.AllFiles() {
+			fmt.Fprintf(h, "%d:%s%d:%s", len(f.Filename), f.Filename, len(f.Content), f.Content)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FindDuplicate returns the newest live snippet owned by userID whose content
+// has the given hash, or ErrNoRecord if they don't have one. Burn-after-reading
+// snippets are left out, as opening one would use it up, and so are encrypted
+// ones, whose ciphertext never matches anyway.
+func (m *SnippetModel) FindDuplicate(userID int, hash string) (*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+	WHERE user_id = ? AND content_sha256 = ?
+	AND (expires IS NULL OR expires > UTC_TIMESTAMP())
+	AND NOT burn_after_reading AND NOT encrypted
+	ORDER BY id DESC LIMIT 1`
+
+	s, err := scanSnippet(m.DB.QueryRow(stmt, userID, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// ByContentHash returns the live snippets whose content has the given hash,
+// newest first. Other people's snippets are only included if they're public
+// and not password-protected, as otherwise the lookup would confirm what a
+// protected snippet says to anyone who could guess it. The user with the given
+// ID (or 0 for an anonymous visitor) also sees all of their own snippets.
+func (m *SnippetModel) ByContentHash(hash string, userID int) ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+	WHERE content_sha256 = ? AND (expires IS NULL OR expires > UTC_TIMESTAMP())
+	AND NOT burn_after_reading
+	AND ((visibility = 'public' AND hashed_password IS NULL) OR user_id = ?)
+	ORDER BY id DESC`
+
+	return m.query(stmt, hash, userID)
+}
@@ -0,0 +1,38 @@
+package models
+
+import "testing"
+
+func TestContentHash(t *testing.T) {
+	// A snippet without extra files hashes to the SHA-256 of its content,
+	// which is what the migration computes with SHA2(content, 256).
+	got := ContentHash(&Snippet{Content: "hello", Filename: "a.txt"})
+	want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	multi := func(extra string) *Snippet {
+		return &Snippet{
+			Filename: "main.go",
+			Content:  "package main",
+			Files:    []*File{{Filename: "go.mod", Content: extra}},
+		}
+	}
+
+	if ContentHash(multi("module a")) != ContentHash(multi("module a")) {
+		t.Error("identical multi-file snippets hash differently")
+	}
+	if ContentHash(multi("module a")) == ContentHash(multi("module b")) {
+		t.Error("multi-file snippets with different files hash the same")
+	}
+	if ContentHash(multi("module a")) == ContentHash(&Snippet{Content: "package main"}) {
+		t.Error("a multi-file snippet hashes the same as its main file alone")
+	}
+
+	// The boundary between a name and its content has to count.
+	a := &Snippet{Filename: "a", Content: "bc", Files: []*File{{Filename: "x", Content: "y"}}}
+	b := &Snippet{Filename: "ab", Content: "c", Files: []*File{{Filename: "x", Content: "y"}}}
+	if ContentHash(a) == ContentHash(b) {
+		t.Error("moving characters between a filename and its content doesn't change the hash")
+	}
+}
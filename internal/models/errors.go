@@ -14,4 +14,9 @@ var(
 	// Add a new ErrDuplicateEmail error. We'll use this later if a user
 	// tries to signup with an email address that's already in use.
 	ErrDuplicateEmail = errors.New("models: duplicate email")
+
+
+	// ErrInvalidCursor is returned when a pagination cursor has been tampered
+	// with or wasn't produced by SnippetModel.Page().
+	ErrInvalidCursor = errors.New("models: invalid pagination cursor")
 )
\ No newline at end of file
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// A File is one of the named files in a multi-file snippet. The snippet's own
+// Filename, Language and Content make up its first file, at position 0, and
+// any further files are stored in the snippet_files table from position 1.
+// Revisions, diffs and search only ever look at the first file.
+type File struct {
+	Position int
+	Filename string
+	Language string
+	Content  string
+}
+
+// MainFile returns the snippet's own content as a File, at position 0.
+func (s *Snippet) MainFile() *File {
+	return &File{Filename: s.Filename, Language: s.Language, Content: s.Content}
+}
+
+// AllFiles returns every file in the snippet in order: the main file first,
+// followed by the extra files in Files.
+func (s *Snippet) AllFiles() []*File {
+	return append([]*File{s.MainFile()}, s.Files...)
+}
+
+// File returns the file at the given position, or ErrNoRecord if there's no
+// such file. The extra files must already have been loaded with LoadFiles().
+func (s *Snippet) File(position int) (*File, error) {
+	files := s.AllFiles()
+	if position < 0 || position >= len(files) {
+		return nil, ErrNoRecord
+	}
+	return files[position], nil
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so loadFiles() can run
+// inside or outside a transaction.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// insertFiles stores the extra files of a snippet, numbering them from 1 and
+// filling in their Position fields. It must be called inside the transaction
+// which wrote the snippet.
+func insertFiles(tx *sql.Tx, snippetID int, files []*File) error {
+	stmt := `INSERT INTO snippet_files (snippet_id, position, filename, language, content, content_encoding, content_gz)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	for i, f := range files {
+		f.Position = i + 1
+
+		// Big files are compressed, just like the snippet's main content.
+		content, err := encodeContent(f.Content)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(stmt, snippetID, f.Position, f.Filename, f.Language, content.Text, content.Encoding, content.Compressed)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadFiles fills in the Files field of a snippet from the snippet_files
+// table, in order.
+func loadFiles(q querier, s *Snippet) error {
+	stmt := `SELECT position, filename, language, content, content_encoding, content_gz FROM snippet_files
+	WHERE snippet_id = ? ORDER BY position`
+
+	rows, err := q.Query(stmt, s.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	s.Files = []*File{}
+	for rows.Next() {
+		f := &File{}
+		var content storedContent
+		err := rows.Scan(&f.Position, &f.Filename, &f.Language, &content.Text, &content.Encoding, &content.Compressed)
+		if err != nil {
+			return err
+		}
+		f.Content, err = content.decode()
+		if err != nil {
+			return err
+		}
+		s.Files = append(s.Files, f)
+	}
+
+	return rows.Err()
+}
+
+// LoadFiles fills in the Files field of a snippet with its extra files.
+func (m *SnippetModel) LoadFiles(s *Snippet) error {
+	return loadFiles(m.DB, s)
+}
@@ -0,0 +1,196 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// The orders in which Page() can list snippets.
+const (
+	SortNewest   = "newest"
+	SortExpiring = "expiring"
+	SortTitle    = "title"
+)
+
+// SortOrders lists every valid sort order, with the default first.
+var SortOrders = []string{SortNewest, SortExpiring, SortTitle}
+
+// PageParams describes which page of snippets to fetch. At most one of After
+// and Before should be set; they are cursors taken from a previous
+// Pagination and select the page following or preceding it. With neither set
+// the first page is returned.
+type PageParams struct {
+	Sort   string
+	Size   int
+	After  string
+	Before string
+}
+
+// Pagination tells the caller how to reach the neighbouring pages. Prev and
+// Next are empty when there is no page in that direction.
+type Pagination struct {
+	Sort string
+	Size int
+	Prev string
+	Next string
+}
+
+// A cursor marks the position of a snippet in a sort order. Key holds the
+// value of the sort column (unused for SortNewest) and ID breaks ties.
+type cursor struct {
+	Key string `json:"k,omitempty"`
+	ID  int    `json:"i"`
+}
+
+// encode turns the cursor into an opaque URL-safe string.
+func (c cursor) encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// decodeCursor reverses encode(), returning ErrInvalidCursor for anything which
+// didn't come from it.
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.ID < 1 {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// Snippets which never expire have a NULL expiry time, which would sort first
+// and can't be compared in a cursor. So when sorting by expiry, they're
+// treated as if they expire at the end of time instead, and sort last.
+const (
+	endOfTime   = "9999-12-31 23:59:59"
+	expiresSort = "COALESCE(expires, TIMESTAMP '" + endOfTime + "')"
+)
+
+// sortKey returns the cursor for a snippet in the given sort order.
+func sortKey(s *Snippet, sort string) cursor {
+	switch sort {
+	case SortExpiring:
+		if s.NeverExpires() {
+			return cursor{Key: endOfTime, ID: s.ID}
+		}
+		return cursor{Key: s.Expires.UTC().Format(time.DateTime), ID: s.ID}
+	case SortTitle:
+		return cursor{Key: s.Title, ID: s.ID}
+	default:
+		return cursor{ID: s.ID}
+	}
+}
+
+// Page returns one page of live, public snippets in the requested order, using keyset
+// pagination: rather than an OFFSET, each page starts from the sort key of
+// the last row on the previous page, so it stays fast and stable however deep
+// you go and however many snippets are added in the meantime.
+func (m *SnippetModel) Page(p PageParams) ([]*Snippet, *Pagination, error) {
+	if !slices.Contains(SortOrders, p.Sort) {
+		return nil, nil, fmt.Errorf("models: unknown sort order %q", p.Sort)
+	}
+
+	// For each sort order we need the ORDER BY clause, and the comparison
+	// which selects rows after a cursor. Going backwards flips both.
+	var order, reverse, after, before string
+	switch p.Sort {
+	case SortNewest:
+		order, reverse = "id DESC", "id ASC"
+		after, before = "id < ?", "id > ?"
+	case SortExpiring:
+		order, reverse = expiresSort+" ASC, id ASC", expiresSort+" DESC, id DESC"
+		after, before = "("+expiresSort+", id) > (?, ?)", "("+expiresSort+", id) < (?, ?)"
+	case SortTitle:
+		order, reverse = "title ASC, id ASC", "title DESC, id DESC"
+		after, before = "(title, id) > (?, ?)", "(title, id) < (?, ?)"
+	}
+
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+	WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND visibility = 'public' AND NOT burn_after_reading`
+	args := []any{}
+	backwards := p.Before != ""
+
+	if p.After != "" || backwards {
+		raw := p.After
+		cmp := after
+		if backwards {
+			raw = p.Before
+			cmp = before
+		}
+
+		c, err := decodeCursor(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		stmt += ` AND ` + cmp
+		if p.Sort != SortNewest {
+			args = append(args, c.Key)
+		}
+		args = append(args, c.ID)
+	}
+
+	if backwards {
+		stmt += ` ORDER BY ` + reverse
+	} else {
+		stmt += ` ORDER BY ` + order
+	}
+
+	// Fetch one extra row, purely to find out whether there is another page
+	// beyond this one.
+	stmt += ` LIMIT ?`
+	args = append(args, p.Size+1)
+
+	snippets, err := m.query(stmt, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	snippets, pagination := paginate(snippets, p)
+	return snippets, pagination, nil
+}
+
+// paginate takes the rows fetched by Page(), which asks for one more than
+// p.Size, and works out which of them make up the page, in display order, and
+// the cursors for the neighbouring pages. When going backwards from p.Before,
+// the rows are in reverse order.
+func paginate(snippets []*Snippet, p PageParams) ([]*Snippet, *Pagination) {
+	backwards := p.Before != ""
+
+	more := len(snippets) > p.Size
+	if more {
+		snippets = snippets[:p.Size]
+	}
+	if backwards {
+		slices.Reverse(snippets)
+	}
+
+	pagination := &Pagination{Sort: p.Sort, Size: p.Size}
+
+	if len(snippets) > 0 {
+		first, last := snippets[0], snippets[len(snippets)-1]
+
+		// Going forwards, there's a previous page if we started from a
+		// cursor, and a next page if the extra row came back. Going
+		// backwards it's the other way around.
+		if (backwards && more) || (!backwards && p.After != "") {
+			pagination.Prev = sortKey(first, p.Sort).encode()
+		}
+		if backwards || more {
+			pagination.Next = sortKey(last, p.Sort).encode()
+		}
+	}
+
+	return snippets, pagination
+}
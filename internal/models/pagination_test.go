@@ -0,0 +1,204 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursors := []cursor{
+		{ID: 1},
+		{ID: 42, Key: "2030-01-02 03:04:05"},
+		{ID: 7, Key: `A "quoted" title, with ünïcödé`},
+		{ID: 9, Key: endOfTime},
+	}
+
+	for _, c := range cursors {
+		s := c.encode()
+
+		got, err := decodeCursor(s)
+		if err != nil {
+			t.Errorf("%+v: %v", c, err)
+			continue
+		}
+		if got != c {
+			t.Errorf("got %+v; want %+v", got, c)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "Not base64", cursor: "!!!"},
+		{name: "Padded base64", cursor: base64.URLEncoding.EncodeToString([]byte(`{"i":1}`))},
+		{name: "Not JSON", cursor: encode("not json")},
+		{name: "Wrong type", cursor: encode(`{"i":"1"}`)},
+		{name: "No ID", cursor: encode(`{"k":"a"}`)},
+		{name: "Zero ID", cursor: encode(`{"i":0}`)},
+		{name: "Negative ID", cursor: encode(`{"i":-1}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeCursor(tt.cursor)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("got %v; want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestSortKey(t *testing.T) {
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.FixedZone("UTC+2", 2*60*60))
+
+	tests := []struct {
+		name    string
+		snippet *Snippet
+		sort    string
+		want    cursor
+	}{
+		{
+			name:    "Newest",
+			snippet: &Snippet{ID: 3, Title: "T", Expires: expires},
+			sort:    SortNewest,
+			want:    cursor{ID: 3},
+		},
+		{
+			name:    "Title",
+			snippet: &Snippet{ID: 3, Title: "T", Expires: expires},
+			sort:    SortTitle,
+			want:    cursor{ID: 3, Key: "T"},
+		},
+		{
+			name:    "Expiring, in UTC",
+			snippet: &Snippet{ID: 3, Title: "T", Expires: expires},
+			sort:    SortExpiring,
+			want:    cursor{ID: 3, Key: "2030-01-02 01:04:05"},
+		},
+		{
+			name:    "Expiring, never",
+			snippet: &Snippet{ID: 3, Title: "T"},
+			sort:    SortExpiring,
+			want:    cursor{ID: 3, Key: endOfTime},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sortKey(tt.snippet, tt.sort); got != tt.want {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// rows returns snippets with the given IDs, in that order.
+func rows(ids ...int) []*Snippet {
+	snippets := []*Snippet{}
+	for _, id := range ids {
+		snippets = append(snippets, &Snippet{ID: id})
+	}
+	return snippets
+}
+
+func TestPaginate(t *testing.T) {
+	// Cursors for SortNewest only hold the ID, which keeps these readable.
+	key := func(id int) string {
+		return cursor{ID: id}.encode()
+	}
+
+	tests := []struct {
+		name     string
+		rows     []*Snippet
+		params   PageParams
+		wantIDs  []int
+		wantPrev string
+		wantNext string
+	}{
+		{
+			name:    "No snippets",
+			rows:    rows(),
+			params:  PageParams{Size: 3},
+			wantIDs: []int{},
+		},
+		{
+			name:    "Only page",
+			rows:    rows(9, 8),
+			params:  PageParams{Size: 3},
+			wantIDs: []int{9, 8},
+		},
+		{
+			name:     "First page",
+			rows:     rows(9, 8, 7, 6),
+			params:   PageParams{Size: 3},
+			wantIDs:  []int{9, 8, 7},
+			wantNext: key(7),
+		},
+		{
+			name:     "Middle page",
+			rows:     rows(6, 5, 4, 3),
+			params:   PageParams{Size: 3, After: key(7)},
+			wantIDs:  []int{6, 5, 4},
+			wantPrev: key(6),
+			wantNext: key(4),
+		},
+		{
+			name:     "Last page",
+			rows:     rows(3, 2),
+			params:   PageParams{Size: 3, After: key(4)},
+			wantIDs:  []int{3, 2},
+			wantPrev: key(3),
+		},
+		{
+			name:     "Back to a middle page",
+			rows:     rows(4, 5, 6, 7),
+			params:   PageParams{Size: 3, Before: key(3)},
+			wantIDs:  []int{6, 5, 4},
+			wantPrev: key(6),
+			wantNext: key(4),
+		},
+		{
+			name:     "Back to the first page",
+			rows:     rows(7, 8, 9),
+			params:   PageParams{Size: 3, Before: key(6)},
+			wantIDs:  []int{9, 8, 7},
+			wantNext: key(7),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params.Sort = SortNewest
+
+			snippets, pagination := paginate(tt.rows, tt.params)
+
+			ids := []int{}
+			for _, s := range snippets {
+				ids = append(ids, s.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("got IDs %v; want %v", ids, tt.wantIDs)
+			}
+
+			if pagination.Prev != tt.wantPrev {
+				t.Errorf("got Prev %q; want %q", pagination.Prev, tt.wantPrev)
+			}
+			if pagination.Next != tt.wantNext {
+				t.Errorf("got Next %q; want %q", pagination.Next, tt.wantNext)
+			}
+			if pagination.Sort != SortNewest || pagination.Size != tt.params.Size {
+				t.Errorf("got Sort %q and Size %d; want %q and %d", pagination.Sort, pagination.Size, SortNewest, tt.params.Size)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Define a Revision type to hold one saved version of a snippet. Every time a
+// snippet is created or edited, its title and content are copied into a new
+// row of the snippet_revisions table, numbered 1, 2, 3... per snippet.
+type Revision struct {
+	SnippetID int
+	Number    int
+	Title     string
+	Content   string
+	Created   time.Time
+}
+
+// Previous returns the number of the revision before this one, or 0 if this
+// is the first revision.
+func (r *Revision) Previous() int {
+	return r.Number - 1
+}
+
+// insertRevision records the given title and content as the next revision of
+// a snippet. It must be called inside the same transaction which changed the
+// snippet, so that the revision numbers can't race with each other. The
+// content has already been through encodeContent(), just like the snippet's.
+func insertRevision(tx *sql.Tx, snippetID int, title string, content storedContent) error {
+	stmt := `INSERT INTO snippet_revisions (snippet_id, revision, title, content, content_encoding, content_gz, created)
+	SELECT ?, IFNULL(MAX(revision), 0) + 1, ?, ?, ?, ?, UTC_TIMESTAMP()
+	FROM snippet_revisions WHERE snippet_id = ?`
+
+	_, err := tx.Exec(stmt, snippetID, title, content.Text, content.Encoding, content.Compressed, snippetID)
+	return err
+}
+
+// revisionColumns lists the columns selected by the queries which return
+// Revision values, in the order that scanRevision() expects them.
+const revisionColumns = `snippet_id, revision, title, content, content_encoding, content_gz, created`
+
+func scanRevision(row rowScanner) (*Revision, error) {
+	r := &Revision{}
+	var content storedContent
+	err := row.Scan(&r.SnippetID, &r.Number, &r.Title, &content.Text, &content.Encoding, &content.Compressed, &r.Created)
+	if err != nil {
+		return nil, err
+	}
+	r.Content, err = content.decode()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Update changes the title and content of a live snippet and stores them as a
+// new revision. If the snippet doesn't exist or has expired, ErrNoRecord is
+// returned.
+func (m *SnippetModel) Update(id int, title, content string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	// Rollback() is a no-op once the transaction has been committed, so it's
+	// safe to always defer it.
+	defer tx.Rollback()
+
+	// Lock the snippet row first. This stops two concurrent edits from
+	// picking the same revision number.
+	s := &Snippet{ID: id, Content: content}
+	stmt := `SELECT filename FROM snippets WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND id = ? FOR UPDATE`
+	err = tx.QueryRow(stmt, id).Scan(&s.Filename)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	// Editing only changes the main file, but the content hash of a
+	// multi-file snippet covers the other files as well.
+	err = loadFiles(tx, s)
+	if err != nil {
+		return err
+	}
+
+	stored, err := encodeContent(content)
+	if err != nil {
+		return err
+	}
+
+	stmt = `UPDATE snippets SET title = ?, content = ?, content_encoding = ?, content_gz = ?,
+	content_sha256 = ? WHERE id = ?`
+	_, err = tx.Exec(stmt, title, stored.Text, stored.Encoding, stored.Compressed, ContentHash(s), id)
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(tx, id, title, stored)
+	if err != nil {
+		return err
+	}
+
+	err = setSearchText(tx, id, title, content)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Restore makes an old revision the current version of a snippet. Rather than
+// rewriting history, the old title and content are saved as a brand new
+// revision, so the restore itself shows up in the history too.
+func (m *SnippetModel) Restore(id, number int) error {
+	rev, err := m.GetRevision(id, number)
+	if err != nil {
+		return err
+	}
+
+	return m.Update(id, rev.Title, rev.Content)
+}
+
+// GetRevision returns a single revision of a snippet.
+func (m *SnippetModel) GetRevision(id, number int) (*Revision, error) {
+	stmt := `SELECT ` + revisionColumns + ` FROM snippet_revisions
+	WHERE snippet_id = ? AND revision = ?`
+
+	r, err := scanRevision(m.DB.QueryRow(stmt, id, number))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Revisions returns every revision of a snippet, newest first.
+func (m *SnippetModel) Revisions(id int) ([]*Revision, error) {
+	stmt := `SELECT ` + revisionColumns + ` FROM snippet_revisions
+	WHERE snippet_id = ? ORDER BY revision DESC`
+
+	rows, err := m.DB.Query(stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*Revision{}
+
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
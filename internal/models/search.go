@@ -0,0 +1,157 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A SearchResult is a snippet which matched a search, along with its
+// relevance score and an excerpt of its content around the first match.
+type SearchResult struct {
+	Snippet *Snippet
+	Score   float64
+	Excerpt []Fragment
+}
+
+// A Fragment is a piece of an excerpt. Match is true for the pieces which
+// matched one of the search terms, so that templates can highlight them.
+type Fragment struct {
+	Text  string
+	Match bool
+}
+
+// How much of the content to show around the first match in an excerpt,
+// measured in characters.
+const (
+	excerptBefore = 60
+	excerptAfter  = 180
+)
+
+// Search returns up to limit live, public snippets which match the query,
+// using the FULLTEXT index on snippet_search. Results are ordered by MySQL's
+// relevance score, best first. Password-protected and
+// burn-after-reading snippets are left out, as the excerpts would give their
+// content away, and so are encrypted snippets, whose content is gibberish.
+func (m *SnippetModel) Search(query string, limit int) ([]*SearchResult, error) {
+	// The MATCH() expression appears twice: once in the WHERE clause to use
+	// the index, and once in the SELECT to get the score for ordering. MySQL
+	// notices they're the same and only computes it once.
+	stmt := `SELECT ` + snippetColumns + `,
+		MATCH(snippet_search.title, snippet_search.content) AGAINST(? IN NATURAL LANGUAGE MODE) AS score
+	FROM snippets JOIN snippet_search ON snippet_search.snippet_id = snippets.id
+	WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND visibility = 'public' AND hashed_password IS NULL
+	AND NOT burn_after_reading AND NOT encrypted
+	AND MATCH(snippet_search.title, snippet_search.content) AGAINST(? IN NATURAL LANGUAGE MODE)
+	ORDER BY score DESC, id DESC LIMIT ?`
+
+	rows, err := m.DB.Query(stmt, query, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terms := searchTermsRx(query)
+	results := []*SearchResult{}
+
+	for rows.Next() {
+		r := &SearchResult{}
+		r.Snippet, err = scanSnippet(rows, &r.Score)
+		if err != nil {
+			return nil, err
+		}
+		r.Excerpt = excerpt(r.Snippet.Content, terms)
+		results = append(results, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// setSearchText stores the plain text title and content of a snippet in the
+// snippet_search table, replacing any which were there before. The snippet's
+// own content may be compressed, which MySQL can't search, so this copy is
+// what the FULLTEXT index covers. It must be called in the same transaction
+// which inserted or changed the snippet.
+func setSearchText(tx *sql.Tx, snippetID int, title, content string) error {
+	stmt := `INSERT INTO snippet_search (snippet_id, title, content) VALUES (?, ?, ?)
+	ON DUPLICATE KEY UPDATE title = VALUES(title), content = VALUES(content)`
+
+	_, err := tx.Exec(stmt, snippetID, title, content)
+	return err
+}
+
+// searchTermsRx builds a case-insensitive regular expression which matches any
+// of the words in the query. It returns nil if the query has no words.
+func searchTermsRx(query string) *regexp.Regexp {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return nil
+	}
+
+	for i := range words {
+		words[i] = regexp.QuoteMeta(words[i])
+	}
+
+	return regexp.MustCompile(`(?i)` + strings.Join(words, "|"))
+}
+
+// excerpt cuts a short window out of content around the first match of terms
+// and splits it into fragments, marking every match inside the window. If
+// nothing in the content matches (the title may have matched instead) the
+// window is taken from the start of the content.
+func excerpt(content string, terms *regexp.Regexp) []Fragment {
+	start := 0
+	if terms != nil {
+		if loc := terms.FindStringIndex(content); loc != nil {
+			start = loc[0]
+		}
+	}
+
+	// Step back and forward by whole runes, so we never cut a multi-byte
+	// character in half.
+	from := start
+	for i := 0; i < excerptBefore && from > 0; i++ {
+		_, size := utf8.DecodeLastRuneInString(content[:from])
+		from -= size
+	}
+	to := start
+	for i := 0; i < excerptAfter && to < len(content); i++ {
+		_, size := utf8.DecodeRuneInString(content[to:])
+		to += size
+	}
+
+	window := content[from:to]
+	var fragments []Fragment
+
+	if from > 0 {
+		fragments = append(fragments, Fragment{Text: "…"})
+	}
+
+	last := 0
+	if terms != nil {
+		for _, loc := range terms.FindAllStringIndex(window, -1) {
+			if loc[0] > last {
+				fragments = append(fragments, Fragment{Text: window[last:loc[0]]})
+			}
+			fragments = append(fragments, Fragment{Text: window[loc[0]:loc[1]], Match: true})
+			last = loc[1]
+		}
+	}
+	if last < len(window) {
+		fragments = append(fragments, Fragment{Text: window[last:]})
+	}
+
+	if to < len(content) {
+		fragments = append(fragments, Fragment{Text: "…"})
+	}
+
+	return fragments
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSearchTermsRx(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string // "" means no regular expression at all.
+	}{
+		{query: "", want: ""},
+		{query: "  +-*  ", want: ""},
+		{query: "frog", want: "(?i)frog"},
+		{query: "frog pond", want: "(?i)frog|pond"},
+		{query: "+frog -toad*", want: "(?i)frog|toad"},
+		{query: "c.d", want: "(?i)c|d"},
+		{query: "Größe", want: "(?i)Größe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rx := searchTermsRx(tt.query)
+
+			got := ""
+			if rx != nil {
+				got = rx.String()
+			}
+			if got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// show writes out fragments as text, with matches in [brackets].
+func show(fragments []Fragment) string {
+	var sb strings.Builder
+	for _, f := range fragments {
+		if f.Match {
+			sb.WriteString("[" + f.Text + "]")
+		} else {
+			sb.WriteString(f.Text)
+		}
+	}
+	return sb.String()
+}
+
+func TestExcerpt(t *testing.T) {
+	long := strings.Repeat("a", 100) + " frog " + strings.Repeat("b", 300)
+
+	tests := []struct {
+		name    string
+		content string
+		query   string
+		want    string
+	}{
+		{
+			name:    "Short content",
+			content: "the frog sat on the log",
+			query:   "frog",
+			want:    "the [frog] sat on the log",
+		},
+		{
+			name:    "Every match is marked",
+			content: "Frog and frog and FROG",
+			query:   "frog",
+			want:    "[Frog] and [frog] and [FROG]",
+		},
+		{
+			name:    "Several terms",
+			content: "a frog in a pond",
+			query:   "pond frog",
+			want:    "a [frog] in a [pond]",
+		},
+		{
+			name:    "No match",
+			content: "nothing to see here",
+			query:   "frog",
+			want:    "nothing to see here",
+		},
+		{
+			name:    "No terms",
+			content: "nothing to see here",
+			query:   "",
+			want:    "nothing to see here",
+		},
+		{
+			name:    "Long content",
+			content: long,
+			query:   "frog",
+			want:    "…" + strings.Repeat("a", excerptBefore-1) + " [frog] " + strings.Repeat("b", excerptAfter-5) + "…",
+		},
+		{
+			name:    "No match in long content",
+			content: long,
+			query:   "toad",
+			want:    strings.Repeat("a", 100) + " frog " + strings.Repeat("b", excerptAfter-106) + "…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := show(excerpt(tt.content, searchTermsRx(tt.query)))
+			if got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcerptMultiByte(t *testing.T) {
+	// Every character here takes more than one byte, so cutting the window
+	// by bytes would split some of them.
+	content := strings.Repeat("é", 200) + "frog" + strings.Repeat("✓", 400)
+
+	fragments := excerpt(content, searchTermsRx("frog"))
+	for _, f := range fragments {
+		if !utf8.ValidString(f.Text) {
+			t.Errorf("fragment %q isn't valid UTF-8", f.Text)
+		}
+	}
+
+	want := []Fragment{
+		{Text: "…"},
+		{Text: strings.Repeat("é", excerptBefore)},
+		{Text: "frog", Match: true},
+		{Text: strings.Repeat("✓", excerptAfter-4)},
+		{Text: "…"},
+	}
+	if !reflect.DeepEqual(fragments, want) {
+		t.Errorf("got %q; want %q", show(fragments), show(want))
+	}
+}
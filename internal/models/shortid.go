@@ -0,0 +1,89 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Short IDs are the public identifiers of snippets. They're 10 random
+// characters from a URL-safe alphabet, which gives about 59 bits of
+// randomness: far too many to enumerate, unlike the auto-increment id column.
+const (
+	shortIDLength   = 10
+	shortIDAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// How many times Insert() generates a new short ID after a collision before
+// giving up. With 59 bits a single collision is already vanishingly unlikely.
+const shortIDAttempts = 5
+
+// newShortID returns a new random short ID.
+func newShortID() (string, error) {
+	// Only accept bytes below the largest multiple of the alphabet length, so
+	// that every character is equally likely.
+	limit := byte(256 - 256%len(shortIDAlphabet))
+
+	id := make([]byte, 0, shortIDLength)
+	buf := make([]byte, shortIDLength*2)
+
+	for len(id) < shortIDLength {
+		_, err := rand.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if b < limit && len(id) < shortIDLength {
+				id = append(id, shortIDAlphabet[int(b)%len(shortIDAlphabet)])
+			}
+		}
+	}
+
+	// Old snippet URLs used the numeric id, so an all-digit short ID would be
+	// ambiguous. Replacing one character with a letter avoids that.
+	if IsLegacyID(string(id)) {
+		id[0] = 'x'
+	}
+
+	return string(id), nil
+}
+
+// IsLegacyID reports whether a snippet identifier from a URL is one of the old
+// numeric auto-increment ids, rather than a short ID.
+func IsLegacyID(id string) bool {
+	return id != "" && strings.Trim(id, "0123456789") == ""
+}
+
+// GetLegacy returns the live snippet with the given numeric id, but only if it
+// was created before short IDs were introduced, which is when the legacy
+// column was set. Burn-after-reading snippets are never returned, as they're
+// not meant to be found by anyone who wasn't sent the link. Just like Get(),
+// checking who can see the snippet is left to the caller.
+func (m *SnippetModel) GetLegacy(id int) (*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+	WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND id = ?
+	AND legacy AND NOT burn_after_reading`
+
+	s, err := scanSnippet(m.DB.QueryRow(stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// isDuplicateShortID reports whether err is MySQL complaining about a short ID
+// which is already in use in the given table.
+func isDuplicateShortID(err error, table string) bool {
+	var mySQLError *mysql.MySQLError
+	if errors.As(err, &mySQLError) {
+		return mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, table+"_uc_short_id")
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewShortID(t *testing.T) {
+	seen := make(map[string]bool)
+
+	for i := 0; i < 1000; i++ {
+		id, err := newShortID()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(id) != shortIDLength {
+			t.Errorf("%q: got length %d; want %d", id, len(id), shortIDLength)
+		}
+		if strings.Trim(id, shortIDAlphabet) != "" {
+			t.Errorf("%q: contains characters outside the alphabet", id)
+		}
+		if IsLegacyID(id) {
+			t.Errorf("%q: looks like a legacy numeric id", id)
+		}
+		if seen[id] {
+			t.Errorf("%q: generated twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestIsLegacyID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{id: "1", want: true},
+		{id: "123456", want: true},
+		{id: "0123456789", want: true},
+		{id: "", want: false},
+		{id: "x123456789", want: false},
+		{id: "12345678a9", want: false},
+		{id: "aB3dE5gH7j", want: false},
+		{id: "-1", want: false},
+		{id: "1.5", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if got := IsLegacyID(tt.id); got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
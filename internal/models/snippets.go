@@ -3,7 +3,10 @@ package models
 import (
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Define a Snippet type to hold the data for an individual snippet. Notice how
@@ -12,10 +15,118 @@ import (
 
 type Snippet struct{
 	ID		int
+	ShortID	string			// The random public identifier used in URLs. ID is only used internally.
 	Title	string
 	Content string
 	Created time.Time		// once a time.Time value is created, its internal state cannot be changed.
-	Expires time.Time
+	Expires time.Time		// The zero time means the snippet never expires.
+	UserID	int				// ID of the user who created the snippet, 0 for snippets created before ownership existed.
+	Tags	[]string		// Only filled in by Insert() and LoadTags(), not by the queries themselves.
+	Language string			// Name of the language to highlight the content as, or "" for plain text.
+	Format	string			// How the content should be rendered, either FormatText or FormatMarkdown.
+	Visibility string		// Who can see the snippet: VisibilityPublic, VisibilityUnlisted or VisibilityPrivate.
+	HasPassword bool		// Whether a password is needed to read the snippet. The hash itself is never loaded.
+	BurnAfterReading bool	// Whether the snippet is deleted the first time it's read. See Burn().
+	ForkedFrom int			// ID of the snippet this one was forked from, or 0.
+	Filename string			// Optional name of the snippet's main file, like "Dockerfile".
+	Files	[]*File			// Any further files, after the main one. Only filled in by Insert() and LoadFiles().
+	Attachments []*Attachment	// Uploaded files. Only filled in by Insert() and LoadAttachments().
+	Stars	int				// How many users have starred the snippet.
+	Views	int				// How many times the snippet has been viewed. See AddViews().
+	Encrypted bool			// Whether Content was encrypted in the browser. The server never has the key.
+	ContentSHA256 string	// See ContentHash(). Empty for snippets saved before hashes were stored.
+}
+
+// The visibility settings for a snippet. Public snippets are listed everywhere.
+// Unlisted snippets can be viewed by anyone with the link, but never appear in
+// listings or search results. Private snippets can only be seen by their owner.
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+)
+
+// Visibilities lists every valid visibility setting, with the default first.
+var Visibilities = []string{VisibilityPublic, VisibilityUnlisted, VisibilityPrivate}
+
+// VisibleTo() reports whether the user with the given ID (or 0 for an
+// anonymous visitor) is allowed to view the snippet.
+func (s *Snippet) VisibleTo(userID int) bool {
+	if s.Visibility == VisibilityPrivate {
+		return userID != 0 && userID == s.UserID
+	}
+	return true
+}
+
+// The formats a snippet's content can be written in. Text content is shown
+// as-is (syntax highlighted in its Language), while Markdown content is
+// rendered to HTML.
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+)
+
+// Formats lists every valid snippet format, with the default first.
+var Formats = []string{FormatText, FormatMarkdown}
+
+// IsMarkdown() reports whether the snippet's content is written in Markdown.
+func (s *Snippet) IsMarkdown() bool {
+	return s.Format == FormatMarkdown
+}
+
+// NeverExpires() reports whether the snippet is permanent. These have a NULL
+// expires column in the database, and a zero Expires time here.
+func (s *Snippet) NeverExpires() bool {
+	return s.Expires.IsZero()
+}
+
+// Expired() reports whether the snippet has passed its expiry time. Get() and
+// Latest() never return expired snippets, but ByUser() does, so the owner's
+// dashboard uses this to show the status of each one.
+func (s *Snippet) Expired() bool {
+	return !s.NeverExpires() && !s.Expires.After(time.Now())
+}
+
+// snippetColumns lists the columns selected by every query which returns
+// Snippet values, in the order that scanSnippet() expects them. Older rows
+// don't have an owner, so we map a NULL user_id to 0. The number of stars is
+// counted with a subquery, which uses the stars table's snippet_id index.
+// Large content is stored compressed, so the content_encoding and content_gz
+// columns are needed to get it back; see encodeContent().
+const snippetColumns = `snippets.id, snippets.short_id, snippets.title, snippets.content, snippets.created,
+	snippets.expires, IFNULL(snippets.user_id, 0), snippets.language,
+	snippets.format, snippets.visibility, snippets.hashed_password IS NOT NULL,
+	snippets.burn_after_reading, IFNULL(snippets.forked_from, 0), snippets.filename,
+	(SELECT COUNT(*) FROM stars WHERE stars.snippet_id = snippets.id), snippets.views,
+	snippets.encrypted, snippets.content_encoding, snippets.content_gz,
+	IFNULL(snippets.content_sha256, '')`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows, which lets
+// scanSnippet() work for single-row and multi-row queries alike.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSnippet copies the snippetColumns of the current row into a new Snippet.
+// Queries which select further columns after snippetColumns can pass
+// pointers for them as extra.
+func scanSnippet(row rowScanner, extra ...any) (*Snippet, error) {
+	s := &Snippet{}
+	// The expires column is NULL for snippets which never expire, which
+	// can't be scanned into a time.Time directly.
+	var expires sql.NullTime
+	var content storedContent
+	dest := append([]any{&s.ID, &s.ShortID, &s.Title, &content.Text, &s.Created, &expires, &s.UserID, &s.Language, &s.Format, &s.Visibility, &s.HasPassword, &s.BurnAfterReading, &s.ForkedFrom, &s.Filename, &s.Stars, &s.Views, &s.Encrypted, &content.Encoding, &content.Compressed, &s.ContentSHA256}, extra...)
+	err := row.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	s.Expires = expires.Time
+	s.Content, err = content.decode()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 
@@ -25,24 +136,82 @@ type SnippetModel struct{
 }
 
 
-// This will insert a new snippet in the database and return the id of the snippet created
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
+// This will insert a new snippet in the database and return the id of the
+// snippet created. The UserID, Title, Content, Expires, Language, Format,
+// Visibility, BurnAfterReading, ForkedFrom, Filename, Encrypted, Files,
+// Attachments and Tags fields of s are used; a zero Expires time makes a snippet which never expires. If
+// password isn't empty, it will be needed to read the snippet. The ID, ShortID,
+// HasPassword and ContentSHA256 fields of s, and the Position of each of its
+// Files, are filled in on success.
+func (m *SnippetModel) Insert(s *Snippet, password string) (int, error) {
+	// Just like UserModel.Insert(), store a bcrypt hash of the password rather
+	// than the password itself. A nil hash is stored as NULL, meaning the
+	// snippet has no password.
+	var hashedPassword []byte
+	if password != ""{
+		var err error
+		hashedPassword, err = bcrypt.GenerateFromPassword([]byte(password), 12)
+		if err != nil{
+			return 0, err
+		}
+	}
+
+
 	// Write the SQL statement we want to execute. I've split it over two lines
 	// for readability (which is why it's surrounded with backquotes instead
 	// of normal double quotes).
 
-	stmt := `INSERT INTO snippets (title, content, created, expires)
-	VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+	stmt := `INSERT INTO snippets (short_id, user_id, title, content, content_encoding, content_gz,
+		content_sha256, language, format, visibility, hashed_password, burn_after_reading,
+		forked_from, filename, encrypted, created, expires)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP(), ?)`
 
+	// Big content is compressed before it's stored. The hash is always of the
+	// original content, and of the extra files too if there are any.
+	content, err := encodeContent(s.Content)
+	if err != nil{
+		return 0, err
+	}
+	contentHash := ContentHash(s)
+
+	// A NULL expiry time means the snippet never expires, and a NULL
+	// forked_from that it isn't a fork.
+	expires := sql.NullTime{Time: s.Expires.UTC(), Valid: !s.NeverExpires()}
+	forkedFrom := sql.NullInt64{Int64: int64(s.ForkedFrom), Valid: s.ForkedFrom != 0}
 
-	// Use the Exec() method on the embedded connection pool to execute the
+
+	// The snippet and its first revision are written together, so begin a
+	// transaction on the connection pool. Deferring Rollback() is safe because
+	// it does nothing once Commit() has succeeded.
+	tx, err := m.DB.Begin()
+	if err != nil{
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Use the Exec() method on the transaction to execute the
 	// statement. The first parameter is the SQL statement, followed by the
-	// title, content and expiry values for the placeholder parameters. This
+	// short ID, owner, title, content, language, format, visibility, password, burn, fork, filename, encrypted and expiry values for the placeholder parameters. This
 	// method returns a sql.Result type, which contains some basic
 	// information about what happened when the statement was executed.
-	result, err := m.DB.Exec(stmt, title, content, expires)
-	if err != nil{
-		return 0, err
+	// Short IDs are random, so in the unlikely event that one is already in
+	// use we generate another and try again. A failed statement doesn't
+	// abort a MySQL transaction, so we can carry on using tx.
+	var result sql.Result
+	for attempt := 1; ; attempt++ {
+		shortID, err := newShortID()
+		if err != nil{
+			return 0, err
+		}
+
+		result, err = tx.Exec(stmt, shortID, s.UserID, s.Title, content.Text, content.Encoding, content.Compressed, contentHash, s.Language, s.Format, s.Visibility, hashedPassword, s.BurnAfterReading, forkedFrom, s.Filename, s.Encrypted, expires)
+		if err == nil{
+			s.ShortID = shortID
+			break
+		}
+		if !isDuplicateShortID(err, "snippets") || attempt == shortIDAttempts{
+			return 0, err
+		}
 	}
 
 	// Use the LastInsertId() method on the result to get the ID of our
@@ -52,6 +221,45 @@ func (m *SnippetModel) Insert(title string, content string, expires int) (int, e
 		return 0, err
 	}
 
+	// Record the initial title and content as revision 1 of the snippet, and
+	// keep a plain text copy for searching.
+	err = insertRevision(tx, int(id), s.Title, content)
+	if err != nil{
+		return 0, err
+	}
+
+	err = setSearchText(tx, int(id), s.Title, s.Content)
+	if err != nil{
+		return 0, err
+	}
+
+	// And attach its tags, creating any which don't exist yet.
+	err = setTags(tx, int(id), s.Tags)
+	if err != nil{
+		return 0, err
+	}
+
+	// Then store any extra files after the main one, and the details of any
+	// attachments.
+	err = insertFiles(tx, int(id), s.Files)
+	if err != nil{
+		return 0, err
+	}
+
+	err = insertAttachments(tx, int(id), s.Attachments)
+	if err != nil{
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil{
+		return 0, err
+	}
+
+	s.ID = int(id)
+	s.HasPassword = hashedPassword != nil
+	s.ContentSHA256 = contentHash
+
 	// The ID returned has the type int64, so we convert it to an int type
 	// before returning.
 	// In Go, int and int64 are distinct types, and they are not interchangeable.
@@ -61,12 +269,13 @@ func (m *SnippetModel) Insert(title string, content string, expires int) (int, e
 	return int(id), nil
 }
 
-// This will return a specific snippet based on id
+// This will return a specific snippet based on id. It doesn't check who is
+// allowed to see it, so callers must check Snippet.VisibleTo() themselves.
 func(m *SnippetModel) Get(id int)(*Snippet, error){
 	// Write the SQL statement we want to execute. Again, I've split it over two
 	// lines for readability.
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-	WHERE expires > UTC_TIMESTAMP() AND id = ?`
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+	WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND id = ?`
 
 	// Use the QueryRow() method on the connection pool to execute our
 	// SQL statement, passing in the untrusted id variable as the value for the
@@ -74,16 +283,12 @@ func(m *SnippetModel) Get(id int)(*Snippet, error){
 	// holds the result from the database.
 	row := m.DB.QueryRow(stmt, id)
 
-	// Initialize a pointer to a new zeroed Snippet struct.
-	s := &Snippet{}
-
-	// Use row.Scan() to copy the values from each field in sql.Row to the
-	// corresponding field in the Snippet struct. Notice that the arguments
-	// to row.Scan are *pointers* to the place you want to copy the data into,
-	// and the number of arguments must be exactly the same as the number of
-	// columns returned by your statement.
-
-	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	// Use scanSnippet() to copy the values from each field in sql.Row to the
+	// corresponding field in a new Snippet struct. Under the hood this calls
+	// row.Scan() with *pointers* to the Snippet fields, so the number of
+	// arguments must be exactly the same as the number of columns in
+	// snippetColumns.
+	s, err := scanSnippet(row)
 	if err != nil{
 		// If the query returns no rows, then row.Scan() will return a
 		// sql.ErrNoRows error. We use the errors.Is() function check for that
@@ -100,18 +305,64 @@ func(m *SnippetModel) Get(id int)(*Snippet, error){
 	return s, nil
 }
 
+// CheckPassword verifies the password of a password-protected snippet,
+// returning ErrInvalidCredentials if it's wrong (or the snippet has no
+// password at all).
+func (m *SnippetModel) CheckPassword(id int, password string) error {
+	var hashedPassword []byte
+
+	stmt := `SELECT hashed_password FROM snippets WHERE id = ? AND hashed_password IS NOT NULL`
+
+	err := m.DB.QueryRow(stmt, id).Scan(&hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetByShortID returns a specific snippet based on its public short ID. Like
+// Get(), it leaves checking who can see it to the caller.
+func (m *SnippetModel) GetByShortID(shortID string) (*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+	WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND short_id = ?`
+
+	s, err := scanSnippet(m.DB.QueryRow(stmt, shortID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return s, nil
+}
+
 // []*Snippet is a slice of pointers to 'Snippet' structs, 
-// It will return pointer to last 10 most recently created snippet 
-func(m *SnippetModel)Latest() ([]*Snippet, error){
+// It will return pointer to the limit most recently created public snippets.
+// Use Page() to reach the older ones.
+func(m *SnippetModel)Latest(limit int) ([]*Snippet, error){
 	// Write the sql statement we want to execute
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-	WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+	WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND visibility = 'public' AND NOT burn_after_reading
+	ORDER BY id DESC LIMIT ?`
 
 	// Use the Query() method on the connection pool to execute our
 	// SQL statement. This returns a sql.Rows resultset containing the result of
 	// our query.
 
-	rows, err := m.DB.Query(stmt)
+	rows, err := m.DB.Query(stmt, limit)
 	if err != nil{
 		return nil, err
 	}
@@ -132,15 +383,9 @@ func(m *SnippetModel)Latest() ([]*Snippet, error){
 	// resultset automatically closes itself and frees-up the underlying
 	// database connection.
 	for rows.Next(){
-		// Create a pointer to a new zeroed Snippet struct.
-		s := &Snippet{}
-
-		// Use rows.Scan() to copy the values from each field in the row to the
-		// new Snippet object that we created. Again, the arguments to row.Scan()
-		// must be pointers to the place you want to copy the data into, and the
-		// number of arguments must be exactly the same as the number of
-		// columns returned by your statement.
-		err := rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		// Use scanSnippet() to copy the values from each field in the row to
+		// a new Snippet object.
+		s, err := scanSnippet(rows)
 		if err != nil{
 			return nil, err
 		}
@@ -161,4 +406,135 @@ func(m *SnippetModel)Latest() ([]*Snippet, error){
 	// If everything went OK then return the Snippets slice.
 	return snippets, nil
 
-}
\ No newline at end of file
+}
+
+// ByUser returns every snippet owned by userID, newest first. Unlike Latest()
+// this deliberately includes expired, unlisted and private snippets, so that
+// owners can still see everything they've created; use Snippet.Expired() to
+// tell the expired ones apart.
+func (m *SnippetModel) ByUser(userID int) ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+	WHERE user_id = ? ORDER BY id DESC`
+
+	return m.query(stmt, userID)
+}
+
+// Forks returns the live, public snippets which were forked from the snippet
+// with the given ID, newest first.
+func (m *SnippetModel) Forks(id int) ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+	WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND visibility = 'public'
+	AND NOT burn_after_reading AND forked_from = ?
+	ORDER BY id DESC`
+
+	return m.query(stmt, id)
+}
+
+// DeleteExpired permanently deletes up to limit snippets which expired more
+// than grace ago, oldest first, and returns how many it deleted. Keeping the
+// batches small means we never hold locks on a large part of the table. The
+// snippets' revisions, tags and attachments go with them, thanks to ON DELETE
+// CASCADE. The attachment files stay on disk, so DeleteExpired also returns
+// their hashes; see UnusedAttachments().
+func (m *SnippetModel) DeleteExpired(grace time.Duration, limit int) (int, []string, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT id FROM snippets
+	WHERE expires IS NOT NULL AND expires < UTC_TIMESTAMP() - INTERVAL ? SECOND
+	ORDER BY expires LIMIT ? FOR UPDATE`
+
+	rows, err := tx.Query(stmt, int64(grace/time.Second), limit)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	args := []any{}
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			return 0, nil, err
+		}
+		args = append(args, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	if len(args) == 0 {
+		return 0, nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+
+	// Note the attachments before the cascade deletes them.
+	stmt = `SELECT DISTINCT sha256 FROM attachments WHERE snippet_id IN (` + placeholders + `)`
+
+	rows, err = tx.Query(stmt, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	sums := []string{}
+	for rows.Next() {
+		var sum string
+		err := rows.Scan(&sum)
+		if err != nil {
+			return 0, nil, err
+		}
+		sums = append(sums, sum)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM snippets WHERE id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return int(n), sums, nil
+}
+
+// query runs a statement which selects snippetColumns and collects every
+// resulting row into a slice, in the same way as Latest() does by hand.
+func (m *SnippetModel) query(stmt string, args ...any) ([]*Snippet, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snippets := []*Snippet{}
+
+	for rows.Next() {
+		s, err := scanSnippet(rows)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// Define a StarModel type which wraps a database connection pool. Users star
+// the snippets they want to find again later.
+type StarModel struct {
+	DB *sql.DB
+}
+
+// Star marks a snippet as starred by a user. Starring a snippet twice is
+// harmless.
+func (m *StarModel) Star(userID, snippetID int) error {
+	stmt := `INSERT IGNORE INTO stars (user_id, snippet_id, created)
+	VALUES (?, ?, UTC_TIMESTAMP())`
+
+	_, err := m.DB.Exec(stmt, userID, snippetID)
+	return err
+}
+
+// Unstar removes a user's star from a snippet, if they had starred it.
+func (m *StarModel) Unstar(userID, snippetID int) error {
+	_, err := m.DB.Exec(`DELETE FROM stars WHERE user_id = ? AND snippet_id = ?`, userID, snippetID)
+	return err
+}
+
+// Exists reports whether a user has starred a snippet.
+func (m *StarModel) Exists(userID, snippetID int) (bool, error) {
+	var exists bool
+
+	stmt := `SELECT EXISTS(SELECT true FROM stars WHERE user_id = ? AND snippet_id = ?)`
+
+	err := m.DB.QueryRow(stmt, userID, snippetID).Scan(&exists)
+	return exists, err
+}
+
+// StarredBy returns the live snippets a user has starred which they can still
+// see, most recently starred first.
+func (m *SnippetModel) StarredBy(userID int) ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+	INNER JOIN stars ON stars.snippet_id = snippets.id
+	WHERE stars.user_id = ? AND (snippets.expires IS NULL OR snippets.expires > UTC_TIMESTAMP())
+	AND (snippets.visibility <> 'private' OR snippets.user_id = stars.user_id)
+	ORDER BY stars.created DESC, snippets.id DESC`
+
+	return m.query(stmt, userID)
+}
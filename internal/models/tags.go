@@ -0,0 +1,95 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// setTags attaches the named tags to a snippet, creating rows in the tags
+// table for any names we haven't seen before. Any tags the snippet already
+// had are replaced. It must be called inside the transaction which wrote the
+// snippet.
+func setTags(tx *sql.Tx, snippetID int, names []string) error {
+	_, err := tx.Exec(`DELETE FROM snippet_tags WHERE snippet_id = ?`, snippetID)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		// If the tag already exists, the ON DUPLICATE KEY clause sets the
+		// value returned by LastInsertId() to the existing row's ID, so we get
+		// the tag's ID back either way.
+		result, err := tx.Exec(`INSERT INTO tags (name) VALUES (?)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)`, name)
+		if err != nil {
+			return err
+		}
+
+		tagID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT IGNORE INTO snippet_tags (snippet_id, tag_id) VALUES (?, ?)`, snippetID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadTags fills in the Tags field of each of the given snippets with a single
+// query, sorted by name.
+func (m *SnippetModel) LoadTags(snippets ...*Snippet) error {
+	if len(snippets) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*Snippet, len(snippets))
+	args := make([]any, 0, len(snippets))
+	for _, s := range snippets {
+		s.Tags = []string{}
+		byID[s.ID] = s
+		args = append(args, s.ID)
+	}
+
+	// database/sql can't expand a slice into an IN (...) list, so we build a
+	// placeholder for each ID ourselves.
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+
+	stmt := `SELECT snippet_tags.snippet_id, tags.name FROM snippet_tags
+	INNER JOIN tags ON tags.id = snippet_tags.tag_id
+	WHERE snippet_tags.snippet_id IN (` + placeholders + `)
+	ORDER BY tags.name`
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var name string
+		err := rows.Scan(&id, &name)
+		if err != nil {
+			return err
+		}
+		byID[id].Tags = append(byID[id].Tags, name)
+	}
+
+	return rows.Err()
+}
+
+// ByTag returns every live, public snippet with the given tag, newest first.
+func (m *SnippetModel) ByTag(name string) ([]*Snippet, error) {
+	stmt := `SELECT ` + snippetColumns + ` FROM snippets
+	INNER JOIN snippet_tags ON snippet_tags.snippet_id = snippets.id
+	INNER JOIN tags ON tags.id = snippet_tags.tag_id
+	WHERE (snippets.expires IS NULL OR snippets.expires > UTC_TIMESTAMP()) AND snippets.visibility = 'public'
+	AND NOT snippets.burn_after_reading AND tags.name = ?
+	ORDER BY snippets.id DESC`
+
+	return m.query(stmt, name)
+}
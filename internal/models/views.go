@@ -0,0 +1,76 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+)
+
+// The most snippets whose view counts are updated by a single statement, which
+// keeps the statement (and the number of placeholders in it) a sensible size.
+const maxViewsPerUpdate = 500
+
+// AddViews adds to the view counts of several snippets at once. The counts map
+// goes from snippet ID to the number of views to add. Rather than running one
+// UPDATE per snippet, each statement updates a whole batch of snippets with a
+// CASE expression:
+//
+//	UPDATE snippets SET views = views + CASE id WHEN ? THEN ? ... END
+//	WHERE id IN (?, ...)
+//
+// Snippets which have been deleted in the meantime are simply skipped. Each
+// statement is committed on its own, so if one fails the earlier ones have
+// still been applied. In that case AddViews returns the counts which haven't
+// been, so that the caller can try those again without counting any twice.
+func (m *SnippetModel) AddViews(counts map[int]int) (map[int]int, error) {
+	return addViews(m.DB.Exec, counts)
+}
+
+// addViews does the work of AddViews(), running each statement with exec.
+func addViews(exec func(query string, args ...any) (sql.Result, error), counts map[int]int) (map[int]int, error) {
+	// Update the rows in ID order, so that concurrent calls always lock them
+	// in the same order and can't deadlock.
+	ids := make([]int, 0, len(counts))
+	for id, n := range counts {
+		if n > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > maxViewsPerUpdate {
+			batch = batch[:maxViewsPerUpdate]
+		}
+		ids = ids[len(batch):]
+
+		var stmt strings.Builder
+		args := make([]any, 0, len(batch)*3)
+
+		stmt.WriteString(`UPDATE snippets SET views = views + CASE id`)
+		for _, id := range batch {
+			stmt.WriteString(` WHEN ? THEN ?`)
+			args = append(args, id, counts[id])
+		}
+		stmt.WriteString(` ELSE 0 END WHERE id IN (?` + strings.Repeat(`, ?`, len(batch)-1) + `)`)
+		for _, id := range batch {
+			args = append(args, id)
+		}
+
+		_, err := exec(stmt.String(), args...)
+		if err != nil {
+			// Neither this batch nor any after it has been applied.
+			unapplied := make(map[int]int)
+			for _, id := range batch {
+				unapplied[id] = counts[id]
+			}
+			for _, id := range ids {
+				unapplied[id] = counts[id]
+			}
+			return unapplied, err
+		}
+	}
+
+	return nil, nil
+}
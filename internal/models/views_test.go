@@ -0,0 +1,97 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeExec returns an exec function for addViews() which records the IDs in
+// each statement's WHERE clause, and fails the statement numbered failAt
+// (counting from 1), or none if failAt is 0.
+func fakeExec(failAt int) (func(string, ...any) (sql.Result, error), *[][]int) {
+	var applied [][]int
+	calls := 0
+
+	exec := func(query string, args ...any) (sql.Result, error) {
+		calls++
+		if calls == failAt {
+			return nil, errors.New("connection lost")
+		}
+
+		// The args are the WHEN/THEN pairs, then the IDs again.
+		n := len(args) / 3
+		ids := []int{}
+		for _, arg := range args[2*n:] {
+			ids = append(ids, arg.(int))
+		}
+		applied = append(applied, ids)
+		return nil, nil
+	}
+
+	return exec, &applied
+}
+
+func TestAddViews(t *testing.T) {
+	counts := make(map[int]int)
+	for id := 1; id <= 2*maxViewsPerUpdate+10; id++ {
+		counts[id] = id % 7
+	}
+
+	// Snippets with no views to add are left out, so this is how many
+	// statements it takes, and which IDs go in each.
+	var want [][]int
+	batch := []int{}
+	for id := 1; id <= 2*maxViewsPerUpdate+10; id++ {
+		if counts[id] == 0 {
+			continue
+		}
+		batch = append(batch, id)
+		if len(batch) == maxViewsPerUpdate {
+			want = append(want, batch)
+			batch = []int{}
+		}
+	}
+	want = append(want, batch)
+
+	exec, applied := fakeExec(0)
+	unapplied, err := addViews(exec, counts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unapplied) != 0 {
+		t.Errorf("got %d unapplied counts; want none", len(unapplied))
+	}
+	if !reflect.DeepEqual(*applied, want) {
+		t.Errorf("got %d statements; want %d", len(*applied), len(want))
+	}
+}
+
+func TestAddViewsPartialFailure(t *testing.T) {
+	counts := make(map[int]int)
+	for id := 1; id <= 2*maxViewsPerUpdate+10; id++ {
+		counts[id] = 1
+	}
+
+	// The first statement succeeds, and the second fails.
+	exec, applied := fakeExec(2)
+	unapplied, err := addViews(exec, counts)
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	if len(*applied) != 1 {
+		t.Fatalf("got %d statements applied; want 1", len(*applied))
+	}
+
+	// Every count is either applied or handed back, and none is both.
+	for _, id := range (*applied)[0] {
+		if _, ok := unapplied[id]; ok {
+			t.Errorf("the count for %d was applied and also handed back", id)
+		}
+		delete(counts, id)
+	}
+	if !reflect.DeepEqual(unapplied, counts) {
+		t.Errorf("got %d unapplied counts; want %d", len(unapplied), len(counts))
+	}
+}
@@ -3,6 +3,7 @@ package validator
 import (
 	"regexp"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
@@ -16,6 +17,25 @@ var EmailRx = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9
 
 
 
+// TagRx matches a valid tag name: up to 32 lower-case letters, digits and a few
+// punctuation characters which are safe in a URL path, like "go" or "c++".
+var TagRx = regexp.MustCompile(`^[a-z0-9][a-z0-9_.+-]{0,31}$`)
+
+// FilenameRx matches a safe filename for a file in a snippet: up to 100
+// letters, digits, dots, dashes and underscores, not starting with a dot, like
+// "Dockerfile" or "docker-compose.yml". In particular it can't contain a slash.
+var FilenameRx = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]{0,99}$`)
+
+// SHA256Rx matches a SHA-256 hash written as lower-case hex.
+var SHA256Rx = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// CiphertextRx matches content encrypted in the browser by main.js: a version
+// number, then a 12-byte IV and the AES-GCM ciphertext (which is at least
+// its 16-byte tag), both base64url-encoded, like "v1.<iv>.<ciphertext>".
+var CiphertextRx = regexp.MustCompile(`^v1\.[A-Za-z0-9_-]{16}\.[A-Za-z0-9_-]{22,}$`)
+
+
+
 // Add a new NonFieldErrors []string field to the struct, which we will use to 
 // hold any validation errors which are not related to a specific form field(Like "email or password is incorrect")
 type Validator struct {
@@ -68,6 +88,13 @@ func MaxChars(value string, n int) bool {
 	return utf8.RuneCountInString(value) <= n
 }
 
+// MaxBytes() returns true if a value is no more than n bytes long. Unlike
+// MaxChars() this counts bytes rather than characters, which is what matters
+// for how much space the value takes up.
+func MaxBytes(value string, n int) bool {
+	return len(value) <= n
+}
+
 // PermittedInt() returns true if a value is in a list of permitted integers.
 func PermittedInt(value int, permittedValues... int) bool {
 	for i := range permittedValues {
@@ -78,6 +105,17 @@ func PermittedInt(value int, permittedValues... int) bool {
 	return false
 }
 
+// PermittedValue() returns true if a value is in a list of permitted values.
+// It's a generic version of PermittedInt() which works for strings too.
+func PermittedValue[T comparable](value T, permittedValues ...T) bool {
+	for i := range permittedValues {
+		if value == permittedValues[i] {
+			return true
+		}
+	}
+	return false
+}
+
 // Validating the signup form now:
 
 
@@ -93,3 +131,30 @@ func MinChars(value string, n int) bool {
 func Matches(value string, rx *regexp.Regexp) bool {
 	return rx.MatchString(value)
 }
+
+// AllMatch() returns true if every value in a slice matches a provided
+// compiled regular expression pattern.
+func AllMatch(values []string, rx *regexp.Regexp) bool {
+	for _, value := range values {
+		if !rx.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// MaxItems() returns true if a slice contains no more than n values.
+func MaxItems[T any](values []T, n int) bool {
+	return len(values) <= n
+}
+
+// FutureTime() returns true if a time is later than the current time.
+func FutureTime(t time.Time) bool {
+	return t.After(time.Now())
+}
+
+// WithinDuration() returns true if a time is no more than d after the current
+// time.
+func WithinDuration(t time.Time, d time.Duration) bool {
+	return !t.After(time.Now().Add(d))
+}
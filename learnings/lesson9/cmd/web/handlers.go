@@ -7,7 +7,7 @@ import (
 	"net/http"
 	"strconv"
 
-	"github.com/Praveen005/snippetbox/internal/models"
+	"github.com/Praveen005/snippetbox/learnings/lesson9/internal/models"
 )
 
 
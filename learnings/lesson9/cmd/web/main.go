@@ -22,7 +22,7 @@ import (
 	// a Module) so that the import statement looks like this:
 	// "{your-module-path}/internal/models". If you can't remember what module path you 
 	// used, you can find it at the top of the go.mod file.
-	"github.com/Praveen005/snippetbox/internal/models"
+	"github.com/Praveen005/snippetbox/learnings/lesson9/internal/models"
 
 
 	_ "github.com/go-sql-driver/mysql"
@@ -34,4 +34,261 @@ INSERT INTO snippets (title, content, created, expires) VALUES (
 );
 
 
-SELECT * FROM snippets;
\ No newline at end of file
+SELECT * FROM snippets;
+
+-- Give every snippet an owner. Snippets created before this change have no
+-- owner, so the column is nullable, and deleting a user keeps their snippets.
+ALTER TABLE snippets ADD COLUMN user_id INTEGER NULL;
+ALTER TABLE snippets ADD CONSTRAINT fk_snippets_user_id
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX idx_snippets_user_id ON snippets(user_id);
+
+
+-- Keep every saved version of a snippet. Revision 1 is the snippet as it was
+-- first created, and each edit or restore adds the next number.
+CREATE TABLE snippet_revisions (
+    id          INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id  INTEGER NOT NULL,
+    revision    INTEGER NOT NULL,
+    title       VARCHAR(100) NOT NULL,
+    content     TEXT NOT NULL,
+    created     DATETIME NOT NULL,
+    CONSTRAINT snippet_revisions_uc_snippet_revision UNIQUE (snippet_id, revision),
+    CONSTRAINT fk_snippet_revisions_snippet_id
+        FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+
+-- Existing snippets start their history at revision 1.
+INSERT INTO snippet_revisions (snippet_id, revision, title, content, created)
+SELECT id, 1, title, content, created FROM snippets;
+
+
+-- Index the title and content of snippets for full-text search.
+CREATE FULLTEXT INDEX idx_snippets_fulltext ON snippets(title, content);
+
+
+-- Tags are shared between snippets, so they live in their own table and are
+-- linked to snippets through snippet_tags.
+CREATE TABLE tags (
+    id    INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    name  VARCHAR(32) NOT NULL,
+    CONSTRAINT tags_uc_name UNIQUE (name)
+);
+
+CREATE TABLE snippet_tags (
+    snippet_id  INTEGER NOT NULL,
+    tag_id      INTEGER NOT NULL,
+    PRIMARY KEY (snippet_id, tag_id),
+    CONSTRAINT fk_snippet_tags_snippet_id
+        FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE,
+    CONSTRAINT fk_snippet_tags_tag_id
+        FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_snippet_tags_tag_id ON snippet_tags(tag_id);
+
+
+-- The language to syntax highlight a snippet's content as. The empty string
+-- means plain text.
+ALTER TABLE snippets ADD COLUMN language VARCHAR(32) NOT NULL DEFAULT '';
+
+
+-- How a snippet's content is rendered: 'text' (optionally syntax highlighted)
+-- or 'markdown'.
+ALTER TABLE snippets ADD COLUMN format VARCHAR(16) NOT NULL DEFAULT 'text';
+
+
+-- Who can see a snippet: 'public' (listed everywhere), 'unlisted' (anyone with
+-- the link) or 'private' (the owner only).
+ALTER TABLE snippets ADD COLUMN visibility VARCHAR(16) NOT NULL DEFAULT 'public';
+CREATE INDEX idx_snippets_visibility_expires ON snippets(visibility, expires);
+
+
+-- Give every snippet a random, URL-safe short ID to use in URLs instead of the
+-- guessable auto-increment id. Existing snippets get one generated from random
+-- bytes; if the unique constraint ever rejects a duplicate, run it again.
+-- Only the snippets which exist now are marked as legacy, and only those can
+-- still be reached at their old numeric id. Snippets created from here on
+-- have no numeric URL at all, so counting up through ids finds nothing new.
+ALTER TABLE snippets ADD COLUMN legacy BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE snippets SET legacy = TRUE;
+ALTER TABLE snippets ADD COLUMN short_id CHAR(10) NULL;
+UPDATE snippets SET short_id = CONCAT('x',
+    SUBSTRING(REPLACE(REPLACE(TO_BASE64(RANDOM_BYTES(9)), '+', 'A'), '/', 'B'), 1, 9))
+    WHERE short_id IS NULL;
+ALTER TABLE snippets MODIFY short_id CHAR(10) NOT NULL;
+ALTER TABLE snippets ADD CONSTRAINT snippets_uc_short_id UNIQUE (short_id);
+
+
+-- An optional bcrypt hash of a password which has to be entered before a
+-- snippet can be read. Snippets with a password are never shown in search.
+ALTER TABLE snippets ADD COLUMN hashed_password CHAR(60) NULL;
+
+
+-- Burn-after-reading snippets are deleted the first time they're read, and
+-- are never listed.
+ALTER TABLE snippets ADD COLUMN burn_after_reading BOOLEAN NOT NULL DEFAULT FALSE;
+
+
+-- Snippets with a NULL expiry time never expire.
+ALTER TABLE snippets MODIFY expires DATETIME NULL;
+
+
+-- Lets the background reaper find expired snippets quickly.
+CREATE INDEX idx_snippets_expires ON snippets(expires);
+
+
+-- The snippet a fork was copied from. If the original is deleted the fork
+-- stays, it just loses the link.
+ALTER TABLE snippets ADD COLUMN forked_from INT NULL;
+ALTER TABLE snippets ADD CONSTRAINT fk_snippets_forked_from
+    FOREIGN KEY (forked_from) REFERENCES snippets(id) ON DELETE SET NULL;
+
+
+-- Multi-file snippets. The snippet's own content is its first file, which can
+-- optionally be given a name; any further files are kept in snippet_files,
+-- numbered from 1.
+ALTER TABLE snippets ADD COLUMN filename VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE TABLE snippet_files (
+    snippet_id  INTEGER NOT NULL,
+    position    INTEGER NOT NULL,
+    filename    VARCHAR(100) NOT NULL,
+    language    VARCHAR(32) NOT NULL DEFAULT '',
+    content     TEXT NOT NULL,
+    PRIMARY KEY (snippet_id, position),
+    CONSTRAINT fk_snippet_files_snippet_id
+        FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+
+
+-- Files uploaded with a snippet. The content is stored on disk in the
+-- directory given by the -upload-dir flag, named after its SHA-256 hash.
+CREATE TABLE attachments (
+    id           INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id   INTEGER NOT NULL,
+    sha256       CHAR(64) NOT NULL,
+    filename     VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size         BIGINT NOT NULL,
+    created      DATETIME NOT NULL,
+    CONSTRAINT fk_attachments_snippet_id
+        FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_attachments_snippet_sha256 ON attachments(snippet_id, sha256);
+-- Used to check whether a file on disk is still attached to any snippet.
+CREATE INDEX idx_attachments_sha256 ON attachments(sha256);
+
+
+-- Snippets starred by users. A star disappears along with its user or snippet.
+CREATE TABLE stars (
+    user_id     INTEGER NOT NULL,
+    snippet_id  INTEGER NOT NULL,
+    created     DATETIME NOT NULL,
+    PRIMARY KEY (user_id, snippet_id),
+    CONSTRAINT fk_stars_user_id
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_stars_snippet_id
+        FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_stars_snippet_id ON stars(snippet_id);
+
+
+-- Comments left on snippets by users. Comments are deleted along with their
+-- snippet or their author.
+CREATE TABLE comments (
+    id          INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id  INTEGER NOT NULL,
+    user_id     INTEGER NOT NULL,
+    content     TEXT NOT NULL,
+    created     DATETIME NOT NULL,
+    CONSTRAINT fk_comments_snippet_id
+        FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comments_user_id
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_comments_snippet_id ON comments(snippet_id, id);
+
+
+-- How many times each snippet has been viewed. The web application buffers
+-- views in memory and adds them to this column in batches.
+ALTER TABLE snippets ADD COLUMN views INTEGER NOT NULL DEFAULT 0;
+
+
+-- Named, ordered lists of snippets put together by users. Collections have
+-- random short IDs, just like snippets. Deleting a collection leaves its
+-- snippets alone, but removes them from it.
+CREATE TABLE collections (
+    id          INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    short_id    CHAR(10) NOT NULL,
+    user_id     INTEGER NOT NULL,
+    title       VARCHAR(100) NOT NULL,
+    created     DATETIME NOT NULL,
+    CONSTRAINT collections_uc_short_id UNIQUE (short_id),
+    CONSTRAINT fk_collections_user_id
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_collections_user_id ON collections(user_id);
+
+CREATE TABLE collection_snippets (
+    collection_id  INTEGER NOT NULL,
+    snippet_id     INTEGER NOT NULL,
+    position       INTEGER NOT NULL,
+    PRIMARY KEY (collection_id, snippet_id),
+    CONSTRAINT fk_collection_snippets_collection_id
+        FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+    CONSTRAINT fk_collection_snippets_snippet_id
+        FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_collection_snippets_position ON collection_snippets(collection_id, position);
+
+
+-- Whether a snippet's content was encrypted in the browser. The content of
+-- an encrypted snippet is ciphertext, and the key is never sent to us.
+ALTER TABLE snippets ADD COLUMN encrypted BOOLEAN NOT NULL DEFAULT FALSE;
+
+
+-- Allow much bigger content, and store large content gzipped. When
+-- content_encoding is 'gzip' the content column is left empty and the
+-- compressed bytes are in content_gz instead; when it's empty the content is
+-- stored as plain text, just like before. The same goes for revisions and the
+-- extra files of multi-file snippets.
+ALTER TABLE snippets
+    MODIFY content MEDIUMTEXT NOT NULL,
+    ADD COLUMN content_encoding VARCHAR(10) NOT NULL DEFAULT '',
+    ADD COLUMN content_gz MEDIUMBLOB NULL;
+ALTER TABLE snippet_revisions
+    MODIFY content MEDIUMTEXT NOT NULL,
+    ADD COLUMN content_encoding VARCHAR(10) NOT NULL DEFAULT '',
+    ADD COLUMN content_gz MEDIUMBLOB NULL;
+ALTER TABLE snippet_files
+    MODIFY content MEDIUMTEXT NOT NULL,
+    ADD COLUMN content_encoding VARCHAR(10) NOT NULL DEFAULT '',
+    ADD COLUMN content_gz MEDIUMBLOB NULL;
+
+-- MySQL can't search gzipped content, so full-text search moves to a table
+-- holding a plain text copy of each snippet's title and content. The index is
+-- created after copying the existing snippets, which is quicker than keeping
+-- it up to date row by row.
+CREATE TABLE snippet_search (
+    snippet_id  INTEGER NOT NULL PRIMARY KEY,
+    title       VARCHAR(100) NOT NULL,
+    content     MEDIUMTEXT NOT NULL,
+    CONSTRAINT fk_snippet_search_snippet_id
+        FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+INSERT INTO snippet_search (snippet_id, title, content)
+SELECT id, title, content FROM snippets;
+CREATE FULLTEXT INDEX idx_snippet_search_fulltext ON snippet_search(title, content);
+DROP INDEX idx_snippets_fulltext ON snippets;
+
+
+-- The SHA-256 hash of each snippet's content, as lower-case hex, used to spot
+-- duplicates. Existing plain-text snippets are hashed here. Gzipped ones can't
+-- be hashed in SQL, and the hash of a multi-file snippet covers all of its
+-- files (see models.ContentHash()), so those are left NULL until next edited.
+ALTER TABLE snippets ADD COLUMN content_sha256 CHAR(64) NULL;
+UPDATE snippets SET content_sha256 = SHA2(content, 256)
+    WHERE content_encoding = ''
+    AND NOT EXISTS (SELECT 1 FROM snippet_files WHERE snippet_files.snippet_id = snippets.id);
+CREATE INDEX idx_snippets_content_sha256 ON snippets(content_sha256);
@@ -5,4 +5,162 @@ for (var i = 0; i < navLinks.length; i++) {
 		link.classList.add("live");
 		break;
 	}
-}
\ No newline at end of file
+}
+
+// On the create form, the "Add file" button adds another set of fields for a
+// file, copied from the <template> element. The numbers in the field names
+// only have to be unique, so we just keep counting up from the files which
+// are already there.
+var addFile = document.getElementById("add-file");
+if (addFile) {
+	var files = document.getElementById("files");
+	var fileTemplate = document.getElementById("file-template");
+	var nextFile = files.querySelectorAll(".file").length;
+
+	addFile.addEventListener("click", function() {
+		var html = fileTemplate.innerHTML.replace(/__INDEX__/g, nextFile++);
+		files.insertAdjacentHTML("beforeend", html);
+	});
+
+	files.addEventListener("click", function(event) {
+		if (event.target.classList.contains("remove-file")) {
+			event.target.closest(".file").remove();
+		}
+	});
+}
+
+// Encrypted snippets are encrypted and decrypted here in the browser with
+// AES-GCM, so the server only ever sees ciphertext. The 256-bit key is kept
+// in the URL fragment as "#key=...", which browsers never send to the server.
+// Content is stored as "v1.<iv>.<ciphertext>", all in base64url, which is
+// what validator.CiphertextRx checks for on the server.
+function toBase64URL(bytes) {
+	var s = "";
+	for (var i = 0; i < bytes.length; i++) {
+		s += String.fromCharCode(bytes[i]);
+	}
+	return btoa(s).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+}
+
+function fromBase64URL(s) {
+	var binary = atob(s.replace(/-/g, "+").replace(/_/g, "/"));
+	var bytes = new Uint8Array(binary.length);
+	for (var i = 0; i < binary.length; i++) {
+		bytes[i] = binary.charCodeAt(i);
+	}
+	return bytes;
+}
+
+// keyFromHash returns the key from the URL fragment, or "" if there isn't one.
+function keyFromHash() {
+	var match = window.location.hash.match(/key=([A-Za-z0-9_-]+)/);
+	return match ? match[1] : "";
+}
+
+function encryptContent(plaintext) {
+	var iv = crypto.getRandomValues(new Uint8Array(12));
+	var key;
+	return crypto.subtle.generateKey({name: "AES-GCM", length: 256}, true, ["encrypt"]).then(function(k) {
+		key = k;
+		return crypto.subtle.encrypt({name: "AES-GCM", iv: iv}, key, new TextEncoder().encode(plaintext));
+	}).then(function(ciphertext) {
+		return crypto.subtle.exportKey("raw", key).then(function(raw) {
+			return {
+				content: "v1." + toBase64URL(iv) + "." + toBase64URL(new Uint8Array(ciphertext)),
+				key: toBase64URL(new Uint8Array(raw))
+			};
+		});
+	});
+}
+
+function decryptContent(content, key) {
+	var parts = content.split(".");
+	if (parts.length != 3 || parts[0] != "v1") {
+		return Promise.reject(new Error("unknown format"));
+	}
+	return crypto.subtle.importKey("raw", fromBase64URL(key), "AES-GCM", false, ["decrypt"]).then(function(k) {
+		return crypto.subtle.decrypt({name: "AES-GCM", iv: fromBase64URL(parts[1])}, k, fromBase64URL(parts[2]));
+	}).then(function(plaintext) {
+		return new TextDecoder().decode(plaintext);
+	});
+}
+
+// On the create form, encrypt the content just before the form is sent, and
+// put the key in the fragment of the form's action. The server redirects to
+// the new snippet without a fragment of its own, so the browser keeps ours,
+// and the user ends up on the snippet's page with the key in the address bar.
+var encrypted = document.getElementById("encrypted");
+if (encrypted) {
+	var createForm = encrypted.form;
+	var content = createForm.elements.content;
+	var action = createForm.getAttribute("action");
+	var plaintext = null;
+
+	// If the form comes back with errors, it contains the ciphertext we sent,
+	// and the key is in our own fragment. Turn it back into plain text.
+	if (encrypted.checked && keyFromHash() && content.value.indexOf("v1.") == 0) {
+		decryptContent(content.value, keyFromHash()).then(function(text) {
+			content.value = text;
+		});
+	}
+
+	createForm.addEventListener("submit", function(event) {
+		if (!encrypted.checked || plaintext !== null) {
+			return;
+		}
+		event.preventDefault();
+
+		plaintext = content.value;
+		encryptContent(plaintext).then(function(result) {
+			content.value = result.content;
+			createForm.setAttribute("action", action + "#key=" + result.key);
+			createForm.submit();
+		}, function() {
+			plaintext = null;
+			alert("Your browser couldn't encrypt the snippet.");
+		});
+	});
+
+	// Coming back to the page with the Back button shows it as we left it,
+	// with the ciphertext in place, so put the plain text back.
+	window.addEventListener("pageshow", function() {
+		if (plaintext !== null) {
+			content.value = plaintext;
+			createForm.setAttribute("action", action);
+			plaintext = null;
+		}
+	});
+}
+
+// On an encrypted snippet's page, decrypt the content with the key from the
+// fragment. It's shown with textContent, so it can never be treated as HTML.
+var encryptedSnippet = document.querySelector("[data-ciphertext]");
+if (encryptedSnippet) {
+	var decryptStatus = encryptedSnippet.querySelector("p");
+	var pre = encryptedSnippet.querySelector("pre");
+
+	if (!keyFromHash()) {
+		decryptStatus.textContent = "This snippet is encrypted, and the key is missing from the link. Ask whoever shared it for the full link.";
+	} else {
+		decryptContent(encryptedSnippet.getAttribute("data-ciphertext"), keyFromHash()).then(function(text) {
+			pre.querySelector("code").textContent = text;
+			pre.hidden = false;
+			decryptStatus.remove();
+		}, function() {
+			decryptStatus.textContent = "This snippet couldn't be decrypted. Check that you have the full, correct link.";
+		});
+	}
+}
+
+// Forms marked with data-keep-hash add the current URL fragment to their
+// action when they're sent, so that the key to an encrypted snippet survives
+// the redirect (or new page) which follows.
+var keepHashForms = document.querySelectorAll("form[data-keep-hash]");
+for (var i = 0; i < keepHashForms.length; i++) {
+	keepHashForms[i].addEventListener("submit", function(event) {
+		var form = event.currentTarget;
+		if (keyFromHash()) {
+			form.setAttribute("action", form.getAttribute("action").split("#")[0] + "#key=" + keyFromHash());
+		}
+	});
+}